@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"cchuffman/huffman"
+)
+
+// convert rewrites legacy (headerless) files in the current format in place
+func convert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s convert [-codebook file] file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	codebook := flags.String("codebook", "", "the shared codebook of files compressed with -codebook, which are already in the current format")
+
+	parseFlags(flags, args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no files to convert")
+	}
+
+	codebooks, err := readCodebooks(*codebook)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range flags.Args() {
+		if err := convertFile(name, codebooks); err != nil {
+			return fmt.Errorf("failed to convert %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// convertFile writes the converted stream to a temporary file next to the
+// original, verifies that it decodes to the same data and only then renames
+// it over the original, so an interruption never leaves a partial file behind.
+// Files in the current format are left alone, which takes the codebooks to
+// tell for files with Shared codes.
+func convertFile(name string, codebooks []*huffman.SharedCodebook) error {
+	compressed, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	reader, err := huffman.NewReader(bytes.NewReader(compressed), codebooks...)
+	if errors.Is(err, huffman.ErrCodebook) {
+		return fmt.Errorf("%v; pass it with -codebook", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !reader.IsLegacy() {
+		log.Printf("%s is already in the current format", name)
+		return nil
+	}
+
+	original, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	writer := huffman.NewWriter(tmp)
+
+	if _, err := writer.Write(original); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := verify(tmp.Name(), original); err != nil {
+		return fmt.Errorf("converted file does not match the original: %v", err)
+	}
+
+	converted, err := os.Stat(tmp.Name())
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+
+	log.Printf("Converted %s (%d bytes) to the current format (%d bytes)", name, info.Size(), converted.Size())
+
+	return nil
+}
+
+// verify decodes the compressed file name and compares it with expected
+func verify(name string, expected []byte) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := huffman.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	actual, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(expected, actual) {
+		return fmt.Errorf("decoded %d bytes that differ from the %d original bytes", len(actual), len(expected))
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"cchuffman/huffman"
+)
+
+// fileEvaluation is the evaluation of a single file
+type fileEvaluation struct {
+	File string `json:"file"`
+	*huffman.Evaluation
+}
+
+// evaluate trains a code on one file and reports how well it codes others,
+// which shows whether a shared code generalizes
+func evaluate(args []string) error {
+	flags := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s evaluate -train file [-format text|json|csv] file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	train := flags.String("train", "", "the file to train the code on")
+	format := flags.String("format", "text", "the output format: text, json or csv")
+	binary := flags.Bool("bytes", false, "count bytes rather than UTF-8 characters")
+	maxCodeLength := flags.Int("max-code-length", 0, "the maximum length of a code in bits (0 for no limit)")
+
+	parseFlags(flags, args)
+
+	if *train == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a file to train on and files to evaluate")
+	}
+
+	var print func(w io.Writer, evaluations []fileEvaluation) error
+	switch *format {
+	case "text":
+		print = printEvaluationsText
+	case "json":
+		print = printEvaluationsJSON
+	case "csv":
+		print = printEvaluationsCSV
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	alphabet := huffman.Runes
+	if *binary {
+		alphabet = huffman.Bytes
+	}
+
+	ft, err := countFile(*train, alphabet)
+	if err != nil {
+		return err
+	}
+
+	trained, err := huffman.NewTrainedCode(ft, *maxCodeLength)
+	if err != nil {
+		return err
+	}
+
+	evaluations := make([]fileEvaluation, 0, flags.NArg())
+	for _, name := range flags.Args() {
+		ft, err := countFile(name, alphabet)
+		if err != nil {
+			return err
+		}
+
+		evaluation, err := trained.Evaluate(ft)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate %s: %v", name, err)
+		}
+
+		evaluations = append(evaluations, fileEvaluation{File: name, Evaluation: evaluation})
+	}
+
+	return print(os.Stdout, evaluations)
+}
+
+func printEvaluationsText(w io.Writer, evaluations []fileEvaluation) error {
+	writer := tabwriter.NewWriter(w, 1, 1, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(writer, "File\tSymbols\tCross-entropy\tEscapes\tUnseen\tSize\tOwn size\tOwn header\tPenalty\t\n")
+	for _, e := range evaluations {
+		fmt.Fprintf(writer, "%s\t%d\t%.4f\t%d\t%d\t%d\t%d\t%d\t%+.1f%%\t\n", e.File, e.Total, e.CrossEntropy, e.Escapes, e.Unseen, e.Size, e.OptimalSize, e.OptimalHeaderSize, 100*e.Penalty)
+	}
+
+	return writer.Flush()
+}
+
+func printEvaluationsJSON(w io.Writer, evaluations []fileEvaluation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(evaluations)
+}
+
+func printEvaluationsCSV(w io.Writer, evaluations []fileEvaluation) error {
+	writer := csv.NewWriter(w)
+
+	float := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	records := [][]string{
+		{"file", "total", "cross_entropy", "escapes", "unseen", "size", "optimal_size", "optimal_header_size", "penalty"},
+	}
+	for _, e := range evaluations {
+		records = append(records, []string{
+			e.File,
+			strconv.Itoa(e.Total),
+			float(e.CrossEntropy),
+			strconv.Itoa(e.Escapes),
+			strconv.Itoa(e.Unseen),
+			strconv.FormatUint(e.Size, 10),
+			strconv.FormatUint(e.OptimalSize, 10),
+			strconv.FormatUint(e.OptimalHeaderSize, 10),
+			float(e.Penalty),
+		})
+	}
+
+	return writer.WriteAll(records)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"cchuffman/huffman"
+)
+
+// extract writes a range of the original data of an indexed file to stdout,
+// decoding only the blocks (and parts of blocks) that the range covers
+func extract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s extract [-codebook file] -offset n -length n file\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	offset := flags.Int64("offset", 0, "the offset in the original data to extract from")
+	length := flags.Int64("length", -1, "the number of bytes to extract (-1 for the rest of the data)")
+	codebook := flags.String("codebook", "", "the shared codebook the file was compressed with")
+
+	parseFlags(flags, args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a single file")
+	}
+
+	if *offset < 0 {
+		return fmt.Errorf("offset %d is negative", *offset)
+	}
+
+	codebooks, err := readCodebooks(*codebook)
+	if err != nil {
+		return err
+	}
+
+	name := flags.Arg(0)
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := huffman.NewBlockReader(f, info.Size(), codebooks...)
+	if err == huffman.ErrNoIndex {
+		return fmt.Errorf("%s has no index; compress it with -index to extract from it", name)
+	}
+	if errors.Is(err, huffman.ErrCodebook) {
+		return fmt.Errorf("%v; pass it with -codebook", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if uint64(*offset) > reader.Size {
+		return fmt.Errorf("offset %d is outside the %d bytes of %s", *offset, reader.Size, name)
+	}
+
+	end := int64(reader.Size)
+	if *length >= 0 && *length < end-*offset {
+		end = *offset + *length
+	}
+
+	if _, err := io.Copy(os.Stdout, io.NewSectionReader(reader, *offset, end-*offset)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package huffman
+
+import (
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// AdaptiveHuffmanTree is a Huffman tree that is updated after every symbol
+// with the FGK algorithm, so codes follow the frequencies seen so far and
+// neither side needs them in advance. An encoder and a decoder that start from
+// NewAdaptiveHuffmanTree and see the same symbols always hold the same tree.
+//
+// Symbols that have not been seen yet are coded as the code of the NYT ("not
+// yet transmitted") leaf, which has a weight of zero, followed by the symbol
+// itself in a fixed number of bits.
+//
+// The tree maintains the sibling property: numbering the nodes from the root
+// downwards, right to left, lists them in order of non-increasing weight, with
+// siblings numbered next to each other. Incrementing a weight only breaks the
+// order if another node of the same weight is numbered before it, in which case
+// the two are swapped first.
+type AdaptiveHuffmanTree struct {
+	alphabet Alphabet
+	nodes    []adaptiveNode
+	// order lists the nodes by number: the root first and NYT last
+	order  []int
+	leaves map[rune]int
+	nyt    int
+	path   []bool
+}
+
+// adaptiveNode mirrors FrequencyNode with the parent and the position in the
+// numbering that updates need. Nodes are referred to by their index in
+// AdaptiveHuffmanTree.nodes, with -1 for none.
+type adaptiveNode struct {
+	char     rune
+	freq     int
+	left     int
+	right    int
+	parent   int
+	position int
+}
+
+func (n *adaptiveNode) IsLeaf() bool {
+	return n.left == -1 && n.right == -1
+}
+
+// NewAdaptiveHuffmanTree returns a tree that holds nothing but the NYT leaf
+func NewAdaptiveHuffmanTree(alphabet Alphabet) *AdaptiveHuffmanTree {
+	return &AdaptiveHuffmanTree{
+		alphabet: alphabet,
+		nodes:    []adaptiveNode{{left: -1, right: -1, parent: -1}},
+		order:    []int{0},
+		leaves:   make(map[rune]int),
+	}
+}
+
+// symbolBits is the width of a symbol sent after the NYT code. Runes covers
+// escaped bytes, the largest of which is escapeBase+0xFF.
+func (a Alphabet) symbolBits() uint {
+	if a == Bytes {
+		return 8
+	}
+	return 21
+}
+
+// Encode writes the code of s and updates the tree
+func (at *AdaptiveHuffmanTree) Encode(w *BitWriter, s rune) error {
+	leaf, seen := at.leaves[s]
+	if !seen {
+		leaf = at.nyt
+	}
+
+	// The path is found from the leaf up, so it is written in reverse
+	at.path = at.path[:0]
+	for n := leaf; at.nodes[n].parent != -1; n = at.nodes[n].parent {
+		at.path = append(at.path, at.nodes[at.nodes[n].parent].right == n)
+	}
+
+	var value uint64
+	var count uint
+	for i := len(at.path) - 1; i >= 0; i-- {
+		value <<= 1
+		if at.path[i] {
+			value |= 1
+		}
+		count += 1
+		if count == MaxPeekBits {
+			if err := w.WriteBits(value, count); err != nil {
+				return err
+			}
+			value, count = 0, 0
+		}
+	}
+	if err := w.WriteBits(value, count); err != nil {
+		return err
+	}
+
+	if !seen {
+		if err := w.WriteBits(uint64(s), at.alphabet.symbolBits()); err != nil {
+			return err
+		}
+	}
+
+	at.update(s)
+
+	return nil
+}
+
+// Decode reads the code of the next symbol and updates the tree
+func (at *AdaptiveHuffmanTree) Decode(r *BitReader) (rune, error) {
+	n := 0
+	for !at.nodes[n].IsLeaf() {
+		bit, err := r.ReadBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit == Zero {
+			n = at.nodes[n].left
+		} else {
+			n = at.nodes[n].right
+		}
+	}
+
+	s := at.nodes[n].char
+	if n == at.nyt {
+		value, err := r.ReadBits(at.alphabet.symbolBits())
+		if err != nil {
+			return 0, err
+		}
+		s = rune(value)
+
+		_, seen := at.leaves[s]
+		if seen || !at.alphabet.validSymbol(s) {
+			return 0, ErrInvalidCode
+		}
+	}
+
+	at.update(s)
+
+	return s, nil
+}
+
+// validSymbol reports whether s is a symbol that nextSymbol can return
+func (a Alphabet) validSymbol(s rune) bool {
+	if a == Bytes {
+		return s >= 0 && s <= 0xFF
+	}
+	return utf8.ValidRune(s) || (isEscape(s) && s <= escapeBase+0xFF)
+}
+
+// decode decodes a single symbol, so that the tree can be used in place of a
+// decodeTable
+func (at *AdaptiveHuffmanTree) decode(b *BitReader, alphabet Alphabet, p []byte, max uint64) ([]byte, uint64, error) {
+	s, err := at.Decode(b)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return p, 0, err
+	}
+	return alphabet.appendSymbol(p, s), 1, nil
+}
+
+// update adds an occurrence of s to the tree, first giving s a leaf split from
+// NYT if it is new
+func (at *AdaptiveHuffmanTree) update(s rune) {
+	n, seen := at.leaves[s]
+	if !seen {
+		// NYT becomes the parent of a new NYT (on the left) and the new leaf
+		// (on the right), which take the two lowest numbers
+		parent := at.nyt
+		leaf := at.add(adaptiveNode{char: s, parent: parent})
+		nyt := at.add(adaptiveNode{parent: parent})
+		at.nodes[parent].left = nyt
+		at.nodes[parent].right = leaf
+		at.leaves[s] = leaf
+		at.nyt = nyt
+
+		// The new leaf is numbered right after its parent, so the order holds
+		// if it is incremented last. Starting from the parent keeps the order
+		// intact for the searches on the way up.
+		defer func() {
+			at.nodes[leaf].freq += 1
+		}()
+		n = parent
+	}
+
+	for ; n != -1; n = at.nodes[n].parent {
+		// The node numbered first among those of the same weight, which the
+		// order makes the first with a weight no greater than n's. The child
+		// just incremented may outweigh n while NYT is its sibling, as in a
+		// tree of a single symbol, so only the nodes up to n are searched.
+		weight := at.nodes[n].freq
+		leader := at.order[sort.Search(at.nodes[n].position+1, func(i int) bool {
+			return at.nodes[at.order[i]].freq <= weight
+		})]
+
+		if leader != n && leader != at.nodes[n].parent {
+			at.swap(n, leader)
+		}
+
+		at.nodes[n].freq += 1
+	}
+}
+
+// add appends a leaf numbered after every existing node
+func (at *AdaptiveHuffmanTree) add(n adaptiveNode) int {
+	n.left, n.right = -1, -1
+	n.position = len(at.order)
+	at.nodes = append(at.nodes, n)
+	at.order = append(at.order, len(at.nodes)-1)
+	return len(at.nodes) - 1
+}
+
+// swap exchanges the subtrees rooted at a and b along with their numbers
+func (at *AdaptiveHuffmanTree) swap(a, b int) {
+	na, nb := &at.nodes[a], &at.nodes[b]
+
+	pa, pb := &at.nodes[na.parent], &at.nodes[nb.parent]
+	if na.parent == nb.parent {
+		pa.left, pa.right = pa.right, pa.left
+	} else {
+		if pa.left == a {
+			pa.left = b
+		} else {
+			pa.right = b
+		}
+		if pb.left == b {
+			pb.left = a
+		} else {
+			pb.right = a
+		}
+		na.parent, nb.parent = nb.parent, na.parent
+	}
+
+	at.order[na.position], at.order[nb.position] = b, a
+	na.position, nb.position = nb.position, na.position
+}
+
+// Tree returns a copy of the current tree as a HuffmanTree, which gives the
+// current codes through ToLookupTable and can be logged. NYT is left out
+// unless the tree is empty.
+func (at *AdaptiveHuffmanTree) Tree() *HuffmanTree {
+	var build func(n int) *FrequencyNode
+	build = func(n int) *FrequencyNode {
+		node := at.nodes[n]
+		if node.IsLeaf() {
+			if n == at.nyt && n != 0 {
+				return nil
+			}
+			return &FrequencyNode{char: node.char, freq: node.freq}
+		}
+		return &FrequencyNode{
+			freq:  node.freq,
+			left:  build(node.left),
+			right: build(node.right),
+		}
+	}
+
+	return NewHuffmanTree(build(0))
+}
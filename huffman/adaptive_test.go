@@ -0,0 +1,128 @@
+package huffman
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// checkSiblingProperty verifies that the numbering lists the nodes by
+// non-increasing weight with siblings next to each other and that every
+// internal node weighs as much as its children
+func checkSiblingProperty(t *testing.T, at *AdaptiveHuffmanTree) {
+	t.Helper()
+
+	for i, n := range at.order {
+		node := at.nodes[n]
+		if node.position != i {
+			t.Fatalf("Expected node %d at position %d but it records %d", n, i, node.position)
+		}
+		if i > 0 && at.nodes[at.order[i-1]].freq < node.freq {
+			t.Fatalf("Expected non-increasing weights but position %d weighs %d after %d", i, node.freq, at.nodes[at.order[i-1]].freq)
+		}
+		if !node.IsLeaf() {
+			left, right := at.nodes[node.left], at.nodes[node.right]
+			if node.freq != left.freq+right.freq {
+				t.Fatalf("Expected node %d to weigh %d but it weighs %d", n, left.freq+right.freq, node.freq)
+			}
+			if right.position+1 != left.position || left.parent != n || right.parent != n {
+				t.Fatalf("Expected the children of node %d to be numbered next to each other", n)
+			}
+		}
+	}
+
+	if at.order[len(at.order)-1] != at.nyt {
+		t.Fatal("Expected NYT to be numbered last")
+	}
+}
+
+func TestAdaptiveHuffmanTree(t *testing.T) {
+	original, err := os.ReadFile("frequency-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoder := NewAdaptiveHuffmanTree(Runes)
+	data := bytes.Buffer{}
+	writer := NewBitWriter(&data)
+
+	symbols := make([]rune, 0)
+	for p := original; len(p) > 0; {
+		s, size := Runes.nextSymbol(p)
+		p = p[size:]
+		symbols = append(symbols, s)
+
+		if err := encoder.Encode(writer, s); err != nil {
+			t.Fatal(err)
+		}
+		checkSiblingProperty(t, encoder)
+	}
+	if err := writer.Flush(Zero); err != nil {
+		t.Fatal(err)
+	}
+
+	// The codes in use are those of a Huffman tree for the counts so far and
+	// NYT with a count of zero
+	ft := NewFrequencyTable(Runes)
+	if err := ft.Populate(bytes.NewReader(original)); err != nil {
+		t.Fatal(err)
+	}
+	leaves := append(ft.ToList(), &FrequencyNode{char: escapeSymbol})
+	static := NewHuffmanTree(NewPriorityQueue(leaves).ToBinaryTree())
+	adaptive := encoder.Tree()
+
+	length := func(tree *HuffmanTree) int {
+		total := 0
+		for s, code := range tree.ToLookupTable() {
+			total += len(code) * ft.Get(s)
+		}
+		return total
+	}
+	if length(adaptive) != length(static) {
+		t.Errorf("Expected the final tree to be optimal (%d bits) but it codes %d bits", length(static), length(adaptive))
+	}
+
+	decoder := NewAdaptiveHuffmanTree(Runes)
+	reader := NewBitReader(&data)
+	for i, expected := range symbols {
+		s, err := decoder.Decode(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected {
+			t.Fatalf("Expected %q at symbol %d but received %q", expected, i, s)
+		}
+	}
+}
+
+func TestAdaptiveSingleSymbol(t *testing.T) {
+	// Until a second symbol arrives the only leaf outweighs NYT, its sibling,
+	// and so every node numbered after its parent
+	for _, input := range []string{"a", "aaaa", "aaaaaaaab", "\x00\x00\x00"} {
+		encoder := NewAdaptiveHuffmanTree(Bytes)
+		data := bytes.Buffer{}
+		writer := NewBitWriter(&data)
+
+		for i := 0; i < len(input); i++ {
+			if err := encoder.Encode(writer, rune(input[i])); err != nil {
+				t.Fatal(err)
+			}
+			checkSiblingProperty(t, encoder)
+		}
+		if err := writer.Flush(Zero); err != nil {
+			t.Fatal(err)
+		}
+
+		decoder := NewAdaptiveHuffmanTree(Bytes)
+		reader := NewBitReader(&data)
+		for i := 0; i < len(input); i++ {
+			s, err := decoder.Decode(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != rune(input[i]) {
+				t.Fatalf("Expected %q at symbol %d of %q but received %q", input[i], i, input, s)
+			}
+		}
+	}
+}
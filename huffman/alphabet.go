@@ -0,0 +1,164 @@
+package huffman
+
+import (
+	"bufio"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Alphabet determines how the input is split into the symbols that receive
+// Huffman codes
+type Alphabet uint8
+
+const (
+	// Runes codes UTF-8 characters, which suits text. Bytes that are not part
+	// of a valid encoding are escaped rather than replaced, so any input still
+	// round-trips.
+	Runes Alphabet = iota
+	// Bytes codes the 256 byte values, which round-trips any input
+	Bytes
+)
+
+// escapeBase is the first symbol beyond the Unicode range. In the Runes
+// alphabet a byte b that is not valid UTF-8 is coded as the symbol
+// escapeBase+b so that it decodes back to exactly that byte.
+const escapeBase rune = utf8.MaxRune + 1
+
+// escapeMarker never begins a valid UTF-8 encoding, so it introduces an
+// escaped byte wherever the header would otherwise hold a UTF-8 character
+const escapeMarker byte = 0xFF
+
+func isEscape(s rune) bool {
+	return s >= escapeBase
+}
+
+func (a Alphabet) String() string {
+	switch a {
+	case Runes:
+		return "runes"
+	case Bytes:
+		return "bytes"
+	default:
+		return fmt.Sprintf("Alphabet(%d)", uint8(a))
+	}
+}
+
+func (a Alphabet) valid() bool {
+	return a == Runes || a == Bytes
+}
+
+// split returns the bufio.SplitFunc that yields one symbol per token
+func (a Alphabet) split() bufio.SplitFunc {
+	if a == Bytes {
+		return bufio.ScanBytes
+	}
+	return scanRunes
+}
+
+// scanRunes behaves like bufio.ScanRunes except that an invalid byte is
+// returned as is rather than as the encoding of utf8.RuneError
+func scanRunes(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if !atEOF && !utf8.FullRune(data) {
+		return 0, nil, nil
+	}
+
+	_, width := utf8.DecodeRune(data)
+	return width, data[:width], nil
+}
+
+// nextSymbol decodes the first symbol in p and returns it along with the
+// number of bytes it occupies
+func (a Alphabet) nextSymbol(p []byte) (rune, int) {
+	if a == Bytes {
+		return rune(p[0]), 1
+	}
+
+	r, size := utf8.DecodeRune(p)
+	if r == utf8.RuneError && size == 1 {
+		return escapeBase + rune(p[0]), 1
+	}
+	return r, size
+}
+
+// count returns the number of symbols in p. utf8.RuneCount counts each invalid
+// byte once, just as nextSymbol escapes each of them.
+func (a Alphabet) count(p []byte) int {
+	if a == Bytes {
+		return len(p)
+	}
+	return utf8.RuneCount(p)
+}
+
+// boundary returns the length of the longest prefix of p that does not end
+// part way through a symbol, so that a block split there codes the same
+// symbols as the unsplit input. It only returns 0 if p is a single incomplete
+// symbol.
+func (a Alphabet) boundary(p []byte) int {
+	if a == Bytes {
+		return len(p)
+	}
+
+	// Only the final utf8.UTFMax-1 bytes can begin an incomplete character
+	for i := len(p) - 1; i >= 0 && i >= len(p)-(utf8.UTFMax-1); i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return i
+			}
+			break
+		}
+	}
+
+	return len(p)
+}
+
+// appendSymbol appends the original bytes of the symbol s to p
+func (a Alphabet) appendSymbol(p []byte, s rune) []byte {
+	if a == Bytes || isEscape(s) {
+		return append(p, byte(s))
+	}
+	return utf8.AppendRune(p, s)
+}
+
+// writeSymbol writes the symbol s to the header
+func (a Alphabet) writeSymbol(w *BitWriter, s rune) error {
+	if a == Bytes {
+		return w.WriteByte(byte(s))
+	}
+
+	if isEscape(s) {
+		if err := w.WriteByte(escapeMarker); err != nil {
+			return err
+		}
+		return w.WriteByte(byte(s))
+	}
+
+	return w.WriteRune(s)
+}
+
+// readSymbol reads a symbol written by writeSymbol from the header
+func (a Alphabet) readSymbol(r *BitReader) (rune, error) {
+	if a == Bytes {
+		b, err := r.ReadByte()
+		return rune(b), err
+	}
+	s, size, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	// utf8.RuneError with a size of 1 is the escape marker rather than a
+	// U+FFFD character from the input, which is 3 bytes long
+	if s == utf8.RuneError && size == 1 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		return escapeBase + rune(b), nil
+	}
+
+	return s, nil
+}
@@ -0,0 +1,311 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// *NOTE* It was clear that to accomplish the encoding, the program would need to:
+// * write single bits to a buffer/stream/file to signify the tree traversal
+// * write runes to a buffer/stream/file to identify the encoded characters for the decoder
+// * write prefix-free codes bit-by-bit to a buffer/stream/file to compress the input
+// Given my gross ignorance of hands-on bit manipulation, I searched for a Go package
+// that would teach me what these items might look like in code. I stumbled upon
+// [go-bitstream](https://github.com/dgryski/go-bitstream), which was both small and
+// easy for me to read and digest. The below code is essentially a plagiarism of go-bitstream
+// for the purpose of learning and completing this coding challenge
+//
+// Both ends have since moved from a single byte to a 64-bit accumulator: bits
+// are queued most significant bit first in a uint64, so a whole code can be
+// written or looked at with a single shift and mask, and only complete bytes
+// are exchanged with the underlying (buffered) reader or writer.
+
+type Bit bool
+
+var ErrPadding = errors.New("huffman: padding bits are not zero")
+
+const (
+	Zero Bit = false
+	One  Bit = true
+)
+
+// MaxPeekBits is the most bits PeekBits can return at once: the accumulator
+// only refills whole bytes, so up to 7 of its 64 bits may already be taken
+const MaxPeekBits = 56
+
+// BitWriter wraps io.Writer with methods to write single bits, groups of bits
+// and/or runes. Writes are buffered until Flush.
+type BitWriter struct {
+	writer *bufio.Writer
+	// bits holds count pending bits, aligned to the MSB (most significant
+	// bit) so the next bit is written at position 63 - count
+	bits  uint64
+	count uint
+}
+
+func NewBitWriter(writer io.Writer) *BitWriter {
+	return &BitWriter{
+		writer: bufio.NewWriter(writer),
+	}
+}
+
+func (bw *BitWriter) WriteBit(bit Bit) error {
+	if bit {
+		return bw.WriteBits(1, 1)
+	}
+	return bw.WriteBits(0, 1)
+}
+
+// WriteBits writes the n (at most 64) least significant bits of value, most
+// significant bit first
+func (bw *BitWriter) WriteBits(value uint64, n uint) error {
+	if n > 64 {
+		return fmt.Errorf("cannot write %d bits at once", n)
+	}
+
+	// Only the pending bits and whole bytes fit at once, so anything past 56
+	// bits is written in two steps
+	if n > MaxPeekBits {
+		if err := bw.WriteBits(value>>32, n-32); err != nil {
+			return err
+		}
+		return bw.WriteBits(value, 32)
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	// ex. 3 bits are pending (count is 3) and value is 101 (n is 3)
+	// Masking keeps the 3 bits of value, and shifting them left by 64 - 3 - 3
+	// places them right behind the pending bits: 101 + 101 + 0000...
+	value &= 1<<n - 1
+	bw.bits |= value << (64 - bw.count - n)
+	bw.count += n
+
+	// Hand every complete byte to the buffered writer
+	for bw.count >= 8 {
+		if err := bw.writer.WriteByte(byte(bw.bits >> 56)); err != nil {
+			return err
+		}
+		bw.bits <<= 8
+		bw.count -= 8
+	}
+
+	return nil
+}
+
+func (bw *BitWriter) WriteRune(char rune) error {
+	lenBytes := utf8.RuneLen(char)
+	if lenBytes == -1 {
+		return fmt.Errorf("character %q is not valid rune", char)
+	}
+	bytes := make([]byte, lenBytes)
+	utf8.EncodeRune(bytes, char)
+
+	for _, b := range bytes {
+		if err := bw.WriteByte(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteByte writes all 8 bits of b regardless of the current alignment,
+// satisfying io.ByteWriter
+func (bw *BitWriter) WriteByte(b byte) error {
+	return bw.WriteBits(uint64(b), 8)
+}
+
+// Flush pads the pending bits to a whole byte with the given bit and writes
+// everything buffered to the underlying writer
+func (bw *BitWriter) Flush(bit Bit) error {
+	if bw.count > 0 {
+		padding := 8 - bw.count
+		value := uint64(0)
+		if bit {
+			value = 1<<padding - 1
+		}
+		if err := bw.WriteBits(value, padding); err != nil {
+			return err
+		}
+	}
+	return bw.writer.Flush()
+}
+
+// BitReader wraps io.Reader with methods to read single bits, groups of bits
+// and/or runes. Bytes are only taken from the underlying reader as they are
+// needed, but a reader that is not an io.ByteReader is buffered, in which case
+// the BitReader may read beyond what it returns.
+type BitReader struct {
+	reader io.ByteReader
+	// bits holds count unread bits, aligned to the MSB (most significant bit)
+	bits  uint64
+	count uint
+	err   error
+}
+
+func NewBitReader(r io.Reader) *BitReader {
+	reader, ok := r.(io.ByteReader)
+	if !ok {
+		reader = bufio.NewReader(r)
+	}
+	return &BitReader{
+		reader: reader,
+	}
+}
+
+// fill reads whole bytes until at least n bits are available or the
+// underlying reader fails
+func (br *BitReader) fill(n uint) {
+	for br.count < n && br.count <= 56 && br.err == nil {
+		var b byte
+		if b, br.err = br.reader.ReadByte(); br.err == nil {
+			br.bits |= uint64(b) << (56 - br.count)
+			br.count += 8
+		}
+	}
+}
+
+// PeekBits returns the next n (at most MaxPeekBits) bits without consuming
+// them. Bits past the end of the input read as zero; an error is only returned
+// when no bits remain at all or the underlying reader fails.
+func (br *BitReader) PeekBits(n uint) (uint64, error) {
+	if n > MaxPeekBits {
+		return 0, fmt.Errorf("cannot peek %d bits at once", n)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	br.fill(n)
+	if br.count < n && (br.count == 0 || br.err != io.EOF) {
+		return 0, br.err
+	}
+	return br.bits >> (64 - n), nil
+}
+
+// Skip consumes the next n bits. io.ErrUnexpectedEOF is returned if the input
+// ends first.
+func (br *BitReader) Skip(n uint) error {
+	for n > 0 {
+		step := n
+		if step > MaxPeekBits {
+			step = MaxPeekBits
+		}
+		br.fill(step)
+		if br.count < step {
+			br.bits, br.count = 0, 0
+			if br.err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return br.err
+		}
+		br.bits <<= step
+		br.count -= step
+		n -= step
+	}
+	return nil
+}
+
+// ReadBits reads the next n (at most 64) bits and returns them as the least
+// significant bits of the result. io.EOF is returned if the input has already
+// ended and io.ErrUnexpectedEOF if it ends part way.
+func (br *BitReader) ReadBits(n uint) (uint64, error) {
+	if n > 64 {
+		return 0, fmt.Errorf("cannot read %d bits at once", n)
+	}
+
+	if n > MaxPeekBits {
+		high, err := br.ReadBits(n - 32)
+		if err != nil {
+			return 0, err
+		}
+		low, err := br.ReadBits(32)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return high<<32 | low, err
+	}
+
+	value, err := br.PeekBits(n)
+	if err != nil {
+		return 0, err
+	}
+
+	return value, br.Skip(n)
+}
+
+func (br *BitReader) ReadBit() (Bit, error) {
+	bit, err := br.ReadBits(1)
+	return bit != 0, err
+}
+
+// ReadRune reads a single UTF-8 encoded character from the stream regardless
+// of the current bit alignment, satisfying io.RuneReader. As with
+// bufio.Reader, an invalid encoding yields utf8.RuneError with a size of 1; the
+// bytes read to detect it are consumed.
+func (br *BitReader) ReadRune() (rune, int, error) {
+	// runes can be multiple bytes, so keeping a buffer of the bytes read so
+	// far is necessary to handle multi-byte runes (e.g. the legacy header's
+	// control character '⁂', which is 3 bytes)
+	rBuff := bytes.Buffer{}
+	for {
+		// Given the logic of ReadRune should only ever operate at the level of 1 byte
+		// per iteration, the buffer either holds a complete (valid or invalid)
+		// encoding after 1 or more iterations or it needs another byte. Checking
+		// for completeness rather than utf8.RuneError ensures U+FFFD itself and
+		// invalid bytes terminate the loop.
+		if utf8.FullRune(rBuff.Bytes()) {
+			r, size := utf8.DecodeRune(rBuff.Bytes())
+			return r, size, nil
+		}
+
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := rBuff.WriteByte(b); err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// ReadByte reads the next 8 bits regardless of the current alignment,
+// satisfying io.ByteReader
+func (br *BitReader) ReadByte() (byte, error) {
+	b, err := br.ReadBits(8)
+	return byte(b), err
+}
+
+// Flush discards the bits remaining in the current byte so the next read begins
+// on a byte boundary. Writers pad with zeros, so a discarded one bit means the
+// stream is corrupt.
+func (br *BitReader) Flush() error {
+	// The accumulator only ever gains whole bytes, so whatever is not a
+	// multiple of 8 is the rest of the current byte
+	padding := br.count % 8
+	if padding == 0 {
+		return nil
+	}
+
+	value, err := br.ReadBits(padding)
+	if err != nil {
+		return err
+	}
+	if value != 0 {
+		return ErrPadding
+	}
+	return nil
+}
+
+// Reset discards the bits remaining in the current byte without inspecting them
+func (br *BitReader) Reset() {
+	padding := br.count % 8
+	br.bits <<= padding
+	br.count -= padding
+}
@@ -0,0 +1,198 @@
+package huffman
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWritingAndReading(t *testing.T) {
+	testHeader := []rune{'0', '1', 'E', '0', '0', '1', 'U', '1', 'D', '0', '1', 'L', '0', '1', 'C', '0', '0', '1', 'Z', '1', 'K', '1', 'M', '⁂'}
+
+	buf := bytes.Buffer{}
+
+	writer := NewBitWriter(&buf)
+
+	for _, r := range testHeader {
+		switch r {
+		case '0':
+			if err := writer.WriteBit(Zero); err != nil {
+				t.Error(err)
+			}
+		case '1':
+			if err := writer.WriteBit(One); err != nil {
+				t.Error(err)
+			}
+		default:
+			if err := writer.WriteRune(r); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	writer.Flush(Zero)
+
+	reader := NewBitReader(&buf)
+
+	for _, expected := range testHeader {
+		switch expected {
+		case '0':
+			bit, err := reader.ReadBit()
+			if err != nil {
+				t.Error(err)
+			}
+			if bit {
+				t.Errorf("Expected zero but received %v", bit)
+			}
+		case '1':
+			bit, err := reader.ReadBit()
+			if err != nil {
+				t.Error(err)
+			}
+			if !bit {
+				t.Errorf("Expected one but received %v", bit)
+			}
+		default:
+			actual, _, err := reader.ReadRune()
+			if err != nil && err != io.EOF {
+				t.Error(err)
+			}
+			if expected != actual {
+				t.Errorf("Expected %q but received %q", expected, actual)
+			}
+		}
+	}
+
+	if err := reader.Flush(); err != nil && err != io.EOF {
+		t.Error(err)
+	}
+}
+
+func TestReadingInvalidRunes(t *testing.T) {
+	buf := bytes.Buffer{}
+
+	writer := NewBitWriter(&buf)
+
+	// Offset everything by a single bit so the runes straddle bytes
+	if err := writer.WriteBit(One); err != nil {
+		t.Error(err)
+	}
+	for _, b := range []byte{0xFF, 0xEF, 0xBF, 0xBD, 'a'} {
+		if err := writer.WriteByte(b); err != nil {
+			t.Error(err)
+		}
+	}
+	writer.Flush(Zero)
+
+	reader := NewBitReader(&buf)
+
+	if bit, err := reader.ReadBit(); bit != One || err != nil {
+		t.Errorf("Expected one but received %v (%v)", bit, err)
+	}
+
+	expected := []struct {
+		char rune
+		size int
+	}{
+		{char: utf8.RuneError, size: 1},
+		{char: utf8.RuneError, size: 3},
+		{char: 'a', size: 1},
+	}
+
+	for _, e := range expected {
+		actual, size, err := reader.ReadRune()
+		if err != nil {
+			t.Error(err)
+		}
+		if actual != e.char || size != e.size {
+			t.Errorf("Expected %q (%d bytes) but received %q (%d bytes)", e.char, e.size, actual, size)
+		}
+	}
+}
+
+func TestWritingAndReadingBits(t *testing.T) {
+	buf := bytes.Buffer{}
+
+	writer := NewBitWriter(&buf)
+
+	// Every width from 1 to 64 bits, so values straddle bytes at every offset
+	values := make([]uint64, 65)
+	for n := uint(1); n <= 64; n++ {
+		values[n] = 0xA5C3F00F5A3CFF01 >> (64 - n)
+		if err := writer.WriteBits(values[n], n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(Zero); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 + 2 + ... + 64 bits, padded to a whole byte
+	if expected := (64*65/2 + 7) / 8; buf.Len() != expected {
+		t.Errorf("Expected %d bytes but received %d", expected, buf.Len())
+	}
+
+	reader := NewBitReader(&buf)
+
+	for n := uint(1); n <= 64; n++ {
+		if n <= MaxPeekBits {
+			peeked, err := reader.PeekBits(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if peeked != values[n] {
+				t.Errorf("Expected to peek %#x at %d bits but received %#x", values[n], n, peeked)
+			}
+		}
+
+		value, err := reader.ReadBits(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != values[n] {
+			t.Errorf("Expected %#x at %d bits but received %#x", values[n], n, value)
+		}
+	}
+
+	if err := reader.Flush(); err != nil {
+		t.Error(err)
+	}
+	if _, err := reader.ReadBits(1); err != io.EOF {
+		t.Errorf("Expected %v but received %v", io.EOF, err)
+	}
+}
+
+func TestPeekingAndSkippingPastEnd(t *testing.T) {
+	reader := NewBitReader(bytes.NewReader([]byte{0xB0}))
+
+	// Peeking beyond the final byte fills with zeros
+	value, err := reader.PeekBits(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 0xB00 {
+		t.Errorf("Expected %#x but received %#x", 0xB00, value)
+	}
+
+	if err := reader.Skip(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Skip(5); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v but received %v", io.ErrUnexpectedEOF, err)
+	}
+	if _, err := reader.PeekBits(1); err != io.EOF {
+		t.Errorf("Expected %v but received %v", io.EOF, err)
+	}
+}
+
+func TestReadingPadding(t *testing.T) {
+	reader := NewBitReader(bytes.NewReader([]byte{0xA1}))
+
+	if _, err := reader.ReadBits(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Flush(); err != ErrPadding {
+		t.Errorf("Expected %v but received %v", ErrPadding, err)
+	}
+}
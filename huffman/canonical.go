@@ -0,0 +1,120 @@
+package huffman
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxCodeLength bounds the code lengths a header may declare. A Huffman tree
+// only grows this deep when symbol frequencies follow the Fibonacci sequence,
+// which needs more than 2^45 input symbols.
+const maxCodeLength = 64
+
+// CodeLengths returns the depth of every leaf in the tree, i.e. the number of
+// bits in each symbol's code
+func (hf *HuffmanTree) CodeLengths() map[rune]int {
+	lengths := make(map[rune]int)
+
+	var traverse func(n *FrequencyNode, depth int)
+	traverse = func(n *FrequencyNode, depth int) {
+		if n == nil {
+			return
+		}
+
+		if n.IsLeaf() {
+			lengths[n.char] = depth
+			return
+		}
+		traverse(n.left, depth+1)
+		traverse(n.right, depth+1)
+	}
+
+	traverse(hf.root, 0)
+
+	return lengths
+}
+
+// Canonical returns a tree with the same code lengths as hf whose codes are
+// assigned canonically. The shape of a Huffman tree depends on how ties were
+// broken while building it, but canonical codes depend only on the code
+// lengths, so they can be rebuilt from a header that stores nothing else.
+func (hf *HuffmanTree) Canonical() *HuffmanTree {
+	// The lengths of an existing tree always satisfy the Kraft inequality, so
+	// rebuilding them cannot fail
+	tree, _ := NewCanonicalHuffmanTree(hf.CodeLengths())
+	return tree
+}
+
+// sortCanonical returns the symbols sorted by code length and then by symbol,
+// which is the order canonical codes are assigned in
+func sortCanonical(lengths map[rune]int) []rune {
+	symbols := make([]rune, 0, len(lengths))
+	for s := range lengths {
+		symbols = append(symbols, s)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if lengths[a] != lengths[b] {
+			return lengths[a] < lengths[b]
+		}
+		return a < b
+	})
+
+	return symbols
+}
+
+// NewCanonicalHuffmanTree builds the tree of canonical codes for the given
+// code lengths. Walking the symbols in canonical order, each symbol receives
+// the previous symbol's code plus one, shifted left by however many bits its
+// code is longer; the first symbol's code is all zeros. An error is returned
+// if the lengths describe more codes than fit (i.e. the Kraft sum exceeds 1).
+// Without any lengths the tree is empty, rather than a leaf with no symbol.
+func NewCanonicalHuffmanTree(lengths map[rune]int) (*HuffmanTree, error) {
+	if len(lengths) == 0 {
+		return NewHuffmanTree(nil), nil
+	}
+
+	root := &FrequencyNode{}
+
+	code := uint64(0)
+	previous := 0
+	for i, s := range sortCanonical(lengths) {
+		length := lengths[s]
+		if length > maxCodeLength {
+			return nil, fmt.Errorf("code length of %q exceeds %d bits", s, maxCodeLength)
+		}
+
+		if i > 0 {
+			code += 1
+		}
+		code <<= length - previous
+		previous = length
+
+		if length < maxCodeLength && code >= 1<<length {
+			return nil, fmt.Errorf("code lengths are over-subscribed at %q", s)
+		}
+
+		insertCode(root, s, code, length)
+	}
+
+	return NewHuffmanTree(root), nil
+}
+
+// insertCode adds a leaf for s at the path spelled by the length least
+// significant bits of code, most significant bit first
+func insertCode(root *FrequencyNode, s rune, code uint64, length int) {
+	n := root
+	for bit := length - 1; bit >= 0; bit-- {
+		next := &n.left
+		if code&(1<<bit) != 0 {
+			next = &n.right
+		}
+		if *next == nil {
+			*next = &FrequencyNode{}
+		}
+		n = *next
+	}
+
+	n.char = s
+}
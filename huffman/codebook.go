@@ -0,0 +1,142 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ErrCodebook is returned for a stream coded with a SharedCodebook that was
+// not supplied to the reader
+var ErrCodebook = errors.New("huffman: stream needs a codebook that was not supplied")
+
+// A codebook file begins with its own magic bytes and version, so it cannot be
+// mistaken for a stream
+const (
+	codebookMagic   = "\x89HCB"
+	codebookVersion = 1
+)
+
+// SharedCodebook is a code trained on sample data (see NewTrainedCode) and
+// shared by writers and readers ahead of time, so that streams coded with it
+// only record its ID rather than a tree per block. That suits payloads too
+// small to pay for a tree of their own. Symbols that the samples lacked are
+// coded as an escape followed by the symbol itself, so any input can be coded.
+//
+// A codebook is saved by WriteTo and loaded by ReadSharedCodebook. It is laid
+// out as:
+//   - the 4 magic bytes "\x89HCB"
+//   - the codebook version byte
+//   - the alphabet byte
+//   - the length of the escape's code in bits as a byte
+//   - the code lengths of the other symbols as written by
+//     HuffmanTree.WriteHeader
+type SharedCodebook struct {
+	// ID is the CRC-32 of the saved codebook, which identifies it in streams
+	ID       uint32
+	Alphabet Alphabet
+	encoded  []byte
+	tree     *HuffmanTree
+	codes    map[rune]Code
+	table    *decodeTable
+}
+
+// NewSharedCodebook builds the codebook that tc stands for
+func NewSharedCodebook(tc *TrainedCode) (*SharedCodebook, error) {
+	lengths := tc.tree.CodeLengths()
+
+	escape := lengths[escapeSymbol]
+	delete(lengths, escapeSymbol)
+
+	buf := bytes.Buffer{}
+	buf.WriteString(codebookMagic)
+	buf.Write([]byte{codebookVersion, byte(tc.table.alphabet), byte(escape)})
+	w := NewBitWriter(&buf)
+	if err := writeCodeLengths(w, lengths, tc.table.alphabet); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(Zero); err != nil {
+		return nil, err
+	}
+
+	return ReadSharedCodebook(&buf)
+}
+
+// ReadSharedCodebook loads a codebook saved by WriteTo, which must take up all
+// of r
+func ReadSharedCodebook(r io.Reader) (*SharedCodebook, error) {
+	encoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encoded) < len(codebookMagic)+3 || string(encoded[:len(codebookMagic)]) != codebookMagic {
+		return nil, fmt.Errorf("not a codebook")
+	}
+	fields := encoded[len(codebookMagic):]
+	if fields[0] != codebookVersion {
+		return nil, fmt.Errorf("unsupported codebook version %d", fields[0])
+	}
+
+	alphabet := Alphabet(fields[1])
+	if !alphabet.valid() {
+		return nil, fmt.Errorf("invalid alphabet %v", alphabet)
+	}
+
+	escape := int(fields[2])
+	if escape == 0 || escape > maxCodeLength {
+		return nil, fmt.Errorf("invalid escape code length of %d bits", escape)
+	}
+
+	p := bytes.NewReader(fields[3:])
+	b := NewBitReader(p)
+	lengths, err := readCodeLengths(b, alphabet)
+	if err == nil {
+		err = b.Flush()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code lengths: %w", err)
+	}
+	if p.Len() > 0 {
+		return nil, ErrTrailingData
+	}
+	lengths[escapeSymbol] = escape
+
+	tree, err := NewCanonicalHuffmanTree(lengths)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SharedCodebook{
+		ID:       crc32.ChecksumIEEE(encoded),
+		Alphabet: alphabet,
+		encoded:  encoded,
+		tree:     tree,
+		codes:    tree.ToCodeTable(),
+		table:    newDecodeTable(tree),
+	}, nil
+}
+
+// WriteTo saves the codebook to w
+func (cb *SharedCodebook) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(cb.encoded)
+	return int64(n), err
+}
+
+// Tree returns the codebook's tree, in which the escape is the symbol beyond
+// the last escaped byte
+func (cb *SharedCodebook) Tree() *HuffmanTree {
+	return cb.tree
+}
+
+// findCodebook returns the codebook with the given ID
+func findCodebook(id uint32, codebooks []*SharedCodebook) (*SharedCodebook, error) {
+	for _, cb := range codebooks {
+		if cb.ID == id {
+			return cb, nil
+		}
+	}
+	return nil, fmt.Errorf("%w (ID %08x)", ErrCodebook, id)
+}
@@ -0,0 +1,188 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func trainCodebook(t *testing.T, alphabet Alphabet, samples ...string) *SharedCodebook {
+	t.Helper()
+
+	ft := NewFrequencyTable(alphabet)
+	for _, sample := range samples {
+		if err := ft.Populate(bytes.NewReader([]byte(sample))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trained, err := NewTrainedCode(ft, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := NewSharedCodebook(trained)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cb
+}
+
+func compressShared(t *testing.T, original []byte, cb *SharedCodebook, header Header) []byte {
+	t.Helper()
+
+	compressed := bytes.Buffer{}
+	writer := NewWriter(&compressed)
+	writer.Header = header
+	writer.SharedCodebook = cb
+	if _, err := writer.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return compressed.Bytes()
+}
+
+func TestSharedCodebook(t *testing.T) {
+	samples := make([]string, 0)
+	for i := 0; i < 50; i++ {
+		samples = append(samples, fmt.Sprintf(`{"id":%d,"name":"user%d","active":%t}`, i, i*7, i%3 == 0))
+	}
+
+	for _, alphabet := range []Alphabet{Runes, Bytes} {
+		cb := trainCodebook(t, alphabet, samples...)
+
+		// Saving and loading the codebook keeps its ID and codes
+		saved := bytes.Buffer{}
+		if _, err := cb.WriteTo(&saved); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := ReadSharedCodebook(&saved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.ID != cb.ID || loaded.Alphabet != alphabet || len(loaded.codes) != len(cb.codes) {
+			t.Errorf("Expected the loaded codebook to match codebook %08x", cb.ID)
+		}
+		for s, code := range cb.codes {
+			if loaded.codes[s] != code {
+				t.Errorf("Expected the code of %q to be %v but received %v", s, code, loaded.codes[s])
+			}
+		}
+
+		// Symbols missing from the samples are escaped, including escaped
+		// bytes and NUL
+		payloads := []string{
+			`{"id":51,"name":"user357","active":false}`,
+			`{"id":52,"name":"Zoë ☃","note":"\x00\xff"}`,
+			"",
+		}
+		for _, payload := range payloads {
+			compressed := compressShared(t, []byte(payload), loaded, Header{})
+
+			reader, err := NewReader(bytes.NewReader(compressed), cb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reader.Coding != Shared || reader.Codebook != cb.ID {
+				t.Errorf("Expected the header to name codebook %08x but received %+v", cb.ID, reader.Header)
+			}
+			decompressed, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decompressed, []byte(payload)) {
+				t.Errorf("Expected %q but received %q", payload, decompressed)
+			}
+
+		}
+
+		// A payload like the samples is smaller without a tree of its own
+		compressed := compressShared(t, []byte(payloads[0]), cb, Header{})
+		static := compress(t, []byte(payloads[0]), Header{Alphabet: alphabet})
+		if len(compressed) >= len(static) {
+			t.Errorf("Expected the shared codebook to save the tree but received %d bytes rather than %d", len(compressed), len(static))
+		}
+
+		// Decoding needs the codebook that the stream names
+		other := trainCodebook(t, alphabet, "something else entirely")
+		for _, codebooks := range [][]*SharedCodebook{nil, {other}} {
+			if _, err := NewReader(bytes.NewReader(compressed), codebooks...); !errors.Is(err, ErrCodebook) {
+				t.Errorf("Expected %v but received %v", ErrCodebook, err)
+			}
+		}
+		if _, err := NewReader(bytes.NewReader(compressed), other, cb); err != nil {
+			t.Errorf("Expected the named codebook to be found among others but received %v", err)
+		}
+	}
+}
+
+func TestSharedCodebookIndexed(t *testing.T) {
+	cb := trainCodebook(t, Runes, "the quick brown fox jumps over the lazy dog")
+	original := bytes.Repeat([]byte("The Quick Brown Fox Jumps Over The Lazy Dog! "), 100)
+
+	writer := bytes.Buffer{}
+	w := NewWriter(&writer)
+	w.SharedCodebook = cb
+	w.BlockSize = 1000
+	w.Indexed = true
+	w.CheckpointInterval = 100
+	if _, err := w.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressed := writer.Bytes()
+
+	if _, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed))); !errors.Is(err, ErrCodebook) {
+		t.Errorf("Expected %v but received %v", ErrCodebook, err)
+	}
+
+	reader, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed)), cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decompressed := bytes.Buffer{}
+	if _, err := reader.WriteTo(&decompressed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed.Bytes(), original) {
+		t.Error("Expected the blocks to decode to the original data")
+	}
+
+	// Checkpoints work as they do for static codes
+	p := make([]byte, 50)
+	if _, err := reader.ReadAt(p, 2345); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, original[2345:2395]) {
+		t.Errorf("Expected %q but received %q", original[2345:2395], p)
+	}
+}
+
+func TestInvalidCodebook(t *testing.T) {
+	cb := trainCodebook(t, Runes, "abcabcabd")
+	saved := bytes.Buffer{}
+	if _, err := cb.WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	streamHeader := compress(t, []byte("abc"), Header{})
+	invalid := map[string][]byte{
+		"empty":     {},
+		"stream":    streamHeader,
+		"truncated": saved.Bytes()[:saved.Len()-1],
+		"trailing":  append(append([]byte{}, saved.Bytes()...), 0),
+	}
+	for name, p := range invalid {
+		if _, err := ReadSharedCodebook(bytes.NewReader(p)); err == nil {
+			t.Errorf("Expected an error for the %s codebook", name)
+		}
+	}
+}
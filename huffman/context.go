@@ -0,0 +1,269 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// contextCodes are the codes of a block of Context coding. Every symbol but the
+// first is coded with the tree of the symbol preceding it, its context, if that
+// context has a tree of its own, and with the order-0 tree otherwise.
+type contextCodes struct {
+	order0   *HuffmanTree
+	contexts map[rune]*HuffmanTree
+}
+
+// buildContextCodes builds the order-0 tree of ft and a tree for every context
+// whose symbols it codes in fewer bits than the order-0 tree does, counting
+// the bits its code lengths take up in the block. Rare contexts do not pay for
+// a table of their own, so their symbols fall back to the order-0 codes.
+func buildContextCodes(ft *FrequencyTable, limit int) (*contextCodes, error) {
+	order0, err := buildTree(ft, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := &contextCodes{
+		order0:   order0,
+		contexts: make(map[rune]*HuffmanTree),
+	}
+
+	lengths := order0.CodeLengths()
+	width := uint64(indexBits(len(lengths)))
+
+	for _, s := range ft.Contexts() {
+		context := ft.Context(s)
+
+		tree, err := buildTree(context, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		// The table costs the index of its context, the number of its
+		// symbols and, for each symbol, its index and the unary increase of
+		// its code length, which add up to the longest code length
+		successors := uint64(len(context.table))
+		own := width + 8*uint64(uvarintLength(successors)) + successors*(width+1)
+		fallback := uint64(0)
+
+		longest := 0
+		for successor, length := range tree.CodeLengths() {
+			count := uint64(context.table[successor])
+			own += count * uint64(length)
+			fallback += count * uint64(lengths[successor])
+			if length > longest {
+				longest = length
+			}
+		}
+		own += uint64(longest)
+
+		if own < fallback {
+			codes.contexts[s] = tree
+		}
+	}
+
+	return codes, nil
+}
+
+// indexBits returns the number of bits that index one of n symbols
+func indexBits(n int) uint {
+	if n <= 1 {
+		return 0
+	}
+	return uint(bits.Len(uint(n - 1)))
+}
+
+// write writes the code lengths of every tree:
+//   - the code lengths of the order-0 tree as written by
+//     HuffmanTree.WriteHeader, without the padding
+//   - the number of contexts with a tree of their own as a uvarint
+//   - for each of those contexts, in the canonical order of the order-0 tree,
+//     the context's index in that order followed by its code lengths in the
+//     layout of HuffmanTree.WriteHeader, with each symbol written as its index
+//     as well
+//   - zero bits up to the next byte boundary
+//
+// Indices take as many bits as the number of order-0 symbols needs, so a
+// context table of English text spends 7 bits on a symbol rather than 21.
+func (codes *contextCodes) write(w *BitWriter, alphabet Alphabet) error {
+	lengths := codes.order0.CodeLengths()
+	if err := writeCodeLengths(w, lengths, alphabet); err != nil {
+		return err
+	}
+
+	symbols := sortCanonical(lengths)
+	width := indexBits(len(symbols))
+	index := make(map[rune]uint64, len(symbols))
+	for i, s := range symbols {
+		index[s] = uint64(i)
+	}
+
+	if err := writeUvarint(w, uint64(len(codes.contexts))); err != nil {
+		return err
+	}
+
+	writeIndex := func(s rune) error {
+		return w.WriteBits(index[s], width)
+	}
+
+	for _, s := range symbols {
+		tree, ok := codes.contexts[s]
+		if !ok {
+			continue
+		}
+
+		if err := writeIndex(s); err != nil {
+			return err
+		}
+		if err := writeLengths(w, tree.CodeLengths(), writeIndex); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush(Zero)
+}
+
+// readContextCodes reads the code lengths written by contextCodes.write and
+// returns a decoder for them, verifying that no code is longer than limit
+// unless it is zero
+func readContextCodes(r *BitReader, alphabet Alphabet, limit int) (*contextDecoder, error) {
+	lengths, err := readCodeLengths(r, alphabet)
+	if err != nil {
+		return nil, err
+	}
+	if len(lengths) == 0 {
+		return nil, fmt.Errorf("no symbols have codes")
+	}
+
+	order0, err := newCanonicalDecodeTable(lengths, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := sortCanonical(lengths)
+	width := indexBits(len(symbols))
+	index := make(map[rune]int, len(symbols))
+	for i, s := range symbols {
+		index[s] = i
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(symbols)) {
+		return nil, fmt.Errorf("%d context tables for %d symbols", count, len(symbols))
+	}
+
+	readIndex := func() (rune, error) {
+		i, err := r.ReadBits(width)
+		if err != nil {
+			return 0, err
+		}
+		if i >= uint64(len(symbols)) {
+			return 0, fmt.Errorf("symbol index %d out of range", i)
+		}
+		return symbols[i], nil
+	}
+
+	d := &contextDecoder{
+		order0:   order0,
+		contexts: make(map[rune]*decodeTable, count),
+	}
+
+	previous := -1
+	for i := uint64(0); i < count; i++ {
+		s, err := readIndex()
+		if err != nil {
+			return nil, err
+		}
+
+		// Contexts follow the canonical order, so an index that does not
+		// increase is a duplicate or out of order
+		if index[s] <= previous {
+			return nil, fmt.Errorf("context %q is out of order", s)
+		}
+		previous = index[s]
+
+		contextLengths, err := readLengths(r, readIndex)
+		if err != nil {
+			return nil, err
+		}
+		if len(contextLengths) == 0 {
+			return nil, fmt.Errorf("context %q has no symbols", s)
+		}
+
+		if d.contexts[s], err = newCanonicalDecodeTable(contextLengths, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	// Discard the padding so the coded data begins on a byte boundary
+	if err := r.Flush(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// contextDecoder decodes the symbols of a block of Context coding, choosing the
+// tree of each symbol by the symbol before it
+type contextDecoder struct {
+	order0   *decodeTable
+	contexts map[rune]*decodeTable
+	previous rune
+	started  bool
+}
+
+// decode decodes a single symbol, since the table that decodes the next one
+// depends on it
+func (d *contextDecoder) decode(b *BitReader, alphabet Alphabet, p []byte, max uint64) ([]byte, uint64, error) {
+	t := d.order0
+	if context, ok := d.contexts[d.previous]; ok && d.started {
+		t = context
+	}
+
+	s, err := t.decodeSymbol(b)
+	if err != nil {
+		return p, 0, err
+	}
+
+	d.previous, d.started = s, true
+
+	return alphabet.appendSymbol(p, s), 1, nil
+}
+
+// encodeContext writes the code of every symbol in input with the tree of its
+// context, followed by zero padding
+func encodeContext(writer *BitWriter, input []byte, alphabet Alphabet, codes *contextCodes) error {
+	order0 := codes.order0.ToCodeTable()
+	contexts := make(map[rune]map[rune]Code, len(codes.contexts))
+	for s, tree := range codes.contexts {
+		contexts[s] = tree.ToCodeTable()
+	}
+
+	codeTable := order0
+	for len(input) > 0 {
+		r, size := alphabet.nextSymbol(input)
+		input = input[size:]
+
+		code, hasRune := codeTable[r]
+		if !hasRune {
+			return fmt.Errorf("failed to lookup %q", r)
+		}
+		if err := writer.WriteBits(code.Bits, code.Length); err != nil {
+			return fmt.Errorf("failed to write code to output for char %q", r)
+		}
+
+		if codeTable = contexts[r]; codeTable == nil {
+			codeTable = order0
+		}
+	}
+
+	if err := writer.Flush(Zero); err != nil {
+		return fmt.Errorf("failed to flush writer: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestContextCoding(t *testing.T) {
+	corpus := benchmarkCorpus(t)
+
+	for _, alphabet := range []Alphabet{Runes, Bytes} {
+		static := compress(t, corpus, Header{Alphabet: alphabet})
+		context := compress(t, corpus, Header{Alphabet: alphabet, Coding: Context})
+
+		decompressed, err := decompress(context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(corpus, decompressed) {
+			t.Fatalf("Expected context coded %v to decompress to the original", alphabet)
+		}
+
+		t.Logf("%v: %d bytes coded in %d bytes with static codes and %d bytes with context codes", alphabet, len(corpus), len(static), len(context))
+
+		if len(context) >= len(static) {
+			t.Errorf("Expected context codes to beat static codes for %v, but received %d bytes for %d", alphabet, len(context), len(static))
+		}
+	}
+
+	ft := NewContextFrequencyTable(Runes)
+	if err := ft.Populate(bytes.NewReader(corpus)); err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := buildContextCodes(ft, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Frequent contexts pay for their own table while the rarest do not
+	if len(codes.contexts) == 0 || len(codes.contexts) == len(ft.Contexts()) {
+		t.Errorf("Expected some but not all of %d contexts to have a table, but %d do", len(ft.Contexts()), len(codes.contexts))
+	}
+	if _, ok := codes.contexts[' ']; !ok {
+		t.Error("Expected a table for the context of a space")
+	}
+}
+
+func TestContextCodingEdgeCases(t *testing.T) {
+	inputs := []string{
+		// The tree of a single symbol, or a context followed by a single
+		// symbol, gives it an empty code
+		"x",
+		"aaaaaaaaaaaaaaaa",
+		"abababababababab",
+		"quiet quilt quota quaint quench quest queue quick quiz",
+		strings.Repeat("the cat sat on the mat. ", 50),
+	}
+
+	for _, input := range inputs {
+		for _, blockSize := range []int{0, 7} {
+			original := []byte(input)
+			decompressed := roundTrip(t, original, Header{Coding: Context, BlockSize: blockSize})
+
+			if !bytes.Equal(original, decompressed) {
+				t.Errorf("Expected %q but received %q", original, decompressed)
+			}
+		}
+	}
+}
+
+func TestContextCodingMaxCodeLength(t *testing.T) {
+	corpus := benchmarkCorpus(t)
+
+	ft := NewContextFrequencyTable(Bytes)
+	if err := ft.Populate(bytes.NewReader(corpus)); err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := buildContextCodes(ft, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tree := range append([]*HuffmanTree{codes.order0}, contextTrees(codes)...) {
+		for s, length := range tree.CodeLengths() {
+			if length > 9 {
+				t.Fatalf("Expected codes of at most 9 bits but %q has %d", s, length)
+			}
+		}
+	}
+
+	decompressed := roundTrip(t, corpus, Header{Alphabet: Bytes, Coding: Context, MaxCodeLength: 9})
+	if !bytes.Equal(corpus, decompressed) {
+		t.Error("Expected length-limited context codes to decompress to the original")
+	}
+}
+
+func contextTrees(codes *contextCodes) []*HuffmanTree {
+	trees := make([]*HuffmanTree, 0, len(codes.contexts))
+	for _, tree := range codes.contexts {
+		trees = append(trees, tree)
+	}
+	return trees
+}
+
+func TestContextCodingIndexed(t *testing.T) {
+	original := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 100)
+	compressed := compress(t, original, Header{Coding: Context, BlockSize: 500, Indexed: true})
+
+	reader, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blocks hold no checkpoints, so every read decodes from the block's start
+	for _, entry := range reader.index {
+		if len(entry.checkpoints) != 0 {
+			t.Fatal("Expected no checkpoints for context codes")
+		}
+	}
+
+	p := make([]byte, 100)
+	if _, err := reader.ReadAt(p, 1234); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, original[1234:1334]) {
+		t.Errorf("Expected %q but received %q", original[1234:1334], p)
+	}
+}
+
+func TestInvalidContextCodes(t *testing.T) {
+	lengths := map[rune]int{'a': 1, 'b': 2, 'c': 2}
+
+	// writeTables writes the order-0 lengths above followed by a table for
+	// each of the given context indices, each giving symbols 0 and 1 a bit
+	writeTables := func(contexts ...uint64) []byte {
+		buf := bytes.Buffer{}
+		w := NewBitWriter(&buf)
+		if err := writeCodeLengths(w, lengths, Runes); err != nil {
+			t.Fatal(err)
+		}
+		writeUvarint(w, uint64(len(contexts)))
+		for _, context := range contexts {
+			w.WriteBits(context, 2)
+			writeUvarint(w, 2)
+			w.WriteBits(0b10_00, 4)
+			w.WriteBits(0b0_01, 3)
+		}
+		w.Flush(Zero)
+		return buf.Bytes()
+	}
+
+	d, err := readContextCodes(NewBitReader(bytes.NewReader(writeTables(0, 2))), Runes, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.contexts) != 2 || d.contexts['a'] == nil || d.contexts['c'] == nil {
+		t.Errorf("Expected tables for the contexts %q and %q", 'a', 'c')
+	}
+
+	invalid := map[string][]byte{
+		"out of order":          writeTables(2, 0),
+		"duplicated":            writeTables(1, 1),
+		"out of range":          writeTables(3),
+		"too many":              writeTables(0, 1, 2, 0),
+		"truncated":             writeTables(0, 2)[:4],
+		"missing order-0 codes": {0, 0},
+	}
+
+	for name, p := range invalid {
+		if _, err := readContextCodes(NewBitReader(bytes.NewReader(p)), Runes, 0); err == nil {
+			t.Errorf("Expected an error for context tables that are %s", name)
+		}
+	}
+
+	if _, err := readContextCodes(NewBitReader(bytes.NewReader(writeTables(0))), Runes, 1); err == nil {
+		t.Error("Expected an error for codes longer than the maximum code length")
+	}
+}
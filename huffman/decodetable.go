@@ -0,0 +1,256 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidCode = errors.New("huffman: invalid code in data")
+
+const (
+	// primaryBits is the number of bits resolved by the first lookup. Longer
+	// codes continue in secondary tables.
+	primaryBits = 10
+	// entrySymbols is the most symbols a single primary lookup decodes
+	entrySymbols = 4
+)
+
+// decodeEntry is the result of looking up the next bits of the data. It either
+// holds one or more complete symbols, along with the number of bits consumed
+// after each of them, or continues in the next table once all of the current
+// table's bits have been consumed.
+type decodeEntry struct {
+	symbols [entrySymbols]rune
+	ends    [entrySymbols]uint8
+	count   uint8
+	next    *decodeTable
+}
+
+// decodeTable maps every value of its next bits (most significant bit first)
+// to the symbols they begin with. Codes that are shorter than the table repeat
+// across every value that shares their prefix, so a single lookup replaces a
+// walk of the tree one bit at a time.
+type decodeTable struct {
+	bits    uint
+	entries []decodeEntry
+}
+
+// newDecodeTable builds the primary table for the tree and, recursively, the
+// secondary tables for codes longer than primaryBits
+func newDecodeTable(tree *HuffmanTree) *decodeTable {
+	// The code of a lone symbol is empty, so a lookup of no bits decodes it
+	if tree.root != nil && tree.root.IsLeaf() {
+		entry := decodeEntry{count: entrySymbols}
+		for i := range entry.symbols {
+			entry.symbols[i] = tree.root.char
+		}
+		return &decodeTable{entries: []decodeEntry{entry}}
+	}
+
+	bits := uint(height(tree.root))
+	if bits > primaryBits {
+		bits = primaryBits
+	}
+	return buildDecodeTable(tree.root, tree.root, bits, true)
+}
+
+// height returns the length of the longest code below n
+func height(n *FrequencyNode) int {
+	if n == nil || n.IsLeaf() {
+		return 0
+	}
+	left, right := height(n.left), height(n.right)
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+// buildDecodeTable resolves bits bits starting from the node start. Primary
+// tables (multi) restart at the root after each symbol to decode as many
+// symbols as fit; secondary tables resolve the one symbol they were built for.
+func buildDecodeTable(root, start *FrequencyNode, bits uint, multi bool) *decodeTable {
+	t := &decodeTable{
+		bits:    bits,
+		entries: make([]decodeEntry, 1<<bits),
+	}
+
+	for i := range t.entries {
+		entry := &t.entries[i]
+		n := start
+
+		for consumed := uint(0); consumed < bits && n != nil; consumed++ {
+			if i&(1<<(bits-1-consumed)) == 0 {
+				n = n.left
+			} else {
+				n = n.right
+			}
+
+			if n != nil && n.IsLeaf() {
+				entry.symbols[entry.count] = n.char
+				entry.ends[entry.count] = uint8(consumed + 1)
+				entry.count += 1
+				if !multi || entry.count == entrySymbols {
+					break
+				}
+				n = root
+			}
+		}
+
+		// The bits ran out inside a code that is too long for this table, so
+		// the remainder is resolved by a table for the subtree reached. Codes
+		// following complete symbols are left for the next lookup instead.
+		if entry.count == 0 && n != nil {
+			remaining := uint(height(n))
+			if remaining > primaryBits {
+				remaining = primaryBits
+			}
+			entry.next = buildDecodeTable(root, n, remaining, false)
+		}
+	}
+
+	return t
+}
+
+// finishData verifies that only the zero padding of the final byte remains
+// of the coded data read by r
+func finishData(r *BitReader) error {
+	if err := r.Flush(); err != nil {
+		return err
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// decode appends up to max symbols to p, using as few lookups as possible,
+// and returns the extended slice along with the number of symbols decoded
+func (t *decodeTable) decode(b *BitReader, alphabet Alphabet, p []byte, max uint64) ([]byte, uint64, error) {
+	table := t
+	for {
+		// Bits past the end of the data read as zero, so a code cut short is
+		// only reported once it is skipped
+		bits, err := b.PeekBits(table.bits)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return p, 0, err
+		}
+		entry := &table.entries[bits]
+
+		if entry.count == 0 {
+			if entry.next == nil {
+				return p, 0, ErrInvalidCode
+			}
+			if err := b.Skip(table.bits); err != nil {
+				return p, 0, err
+			}
+			table = entry.next
+			continue
+		}
+
+		count := uint64(entry.count)
+		if count > max {
+			count = max
+		}
+
+		// The symbol after an escape follows in full, so an escape ends the
+		// symbols of the lookup
+		for i, s := range entry.symbols[:count] {
+			if s == escapeSymbol {
+				count = uint64(i + 1)
+				break
+			}
+		}
+
+		if err := b.Skip(uint(entry.ends[count-1])); err != nil {
+			return p, 0, err
+		}
+
+		for _, s := range entry.symbols[:count] {
+			if s == escapeSymbol {
+				if s, err = readEscaped(b, alphabet); err != nil {
+					return p, 0, err
+				}
+			}
+			p = alphabet.appendSymbol(p, s)
+		}
+
+		return p, count, nil
+	}
+}
+
+// decodeSymbol decodes the single symbol the next bits begin with, for codes
+// where the symbol decides how the next one is decoded
+func (t *decodeTable) decodeSymbol(b *BitReader) (rune, error) {
+	table := t
+	for {
+		bits, err := b.PeekBits(table.bits)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		entry := &table.entries[bits]
+
+		if entry.count == 0 {
+			if entry.next == nil {
+				return 0, ErrInvalidCode
+			}
+			if err := b.Skip(table.bits); err != nil {
+				return 0, err
+			}
+			table = entry.next
+			continue
+		}
+
+		if err := b.Skip(uint(entry.ends[0])); err != nil {
+			return 0, err
+		}
+		return entry.symbols[0], nil
+	}
+}
+
+// newCanonicalDecodeTable builds the table of the canonical codes with the
+// given lengths, verifying that none is longer than limit unless it is zero
+func newCanonicalDecodeTable(lengths map[rune]int, limit int) (*decodeTable, error) {
+	if limit > 0 {
+		for s, length := range lengths {
+			if length > limit {
+				return nil, fmt.Errorf("code length of %q exceeds the maximum of %d bits", s, limit)
+			}
+		}
+	}
+
+	tree, err := NewCanonicalHuffmanTree(lengths)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDecodeTable(tree), nil
+}
+
+// readEscaped reads the symbol that follows the code of an escape, which only
+// the trees of shared codebooks have
+func readEscaped(b *BitReader, alphabet Alphabet) (rune, error) {
+	value, err := b.ReadBits(alphabet.symbolBits())
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	s := rune(value)
+	if !alphabet.validSymbol(s) {
+		return 0, ErrInvalidCode
+	}
+	return s, nil
+}
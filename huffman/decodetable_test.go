@@ -0,0 +1,179 @@
+package huffman
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodeSample builds the canonical tree for sample and returns it with the
+// coded data
+func encodeSample(tb testing.TB, sample []byte, alphabet Alphabet, limit int) (*HuffmanTree, []byte, uint64) {
+	tb.Helper()
+
+	ft := NewFrequencyTable(alphabet)
+	if err := ft.Populate(bytes.NewReader(sample)); err != nil {
+		tb.Fatal(err)
+	}
+
+	tree, err := buildTree(ft, limit)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	data := bytes.Buffer{}
+	if _, err := encode(NewBitWriter(&data), sample, alphabet, tree.ToCodeTable(), 0); err != nil {
+		tb.Fatal(err)
+	}
+
+	return tree, data.Bytes(), uint64(ft.Total())
+}
+
+func decodeWithTable(tree *HuffmanTree, data []byte, alphabet Alphabet, count uint64) ([]byte, error) {
+	table := newDecodeTable(tree)
+	bits := NewBitReader(bytes.NewReader(data))
+
+	out := make([]byte, 0)
+	for count > 0 {
+		var decoded uint64
+		var err error
+		out, decoded, err = table.decode(bits, alphabet, out, count)
+		if err != nil {
+			return out, err
+		}
+		count -= decoded
+	}
+
+	return out, finishData(bits)
+}
+
+// decodeBitByBit decodes the way the Reader did before decode tables: one bit
+// at a time, looking up the code accumulated so far
+func decodeBitByBit(tree *HuffmanTree, data []byte, alphabet Alphabet, count uint64) ([]byte, error) {
+	decoderTable := make(map[string]rune)
+	for r, c := range tree.ToLookupTable() {
+		decoderTable[c] = r
+	}
+
+	reader := NewBitReader(bytes.NewReader(data))
+
+	out := make([]byte, 0)
+	code := ""
+	for count > 0 {
+		bit, err := reader.ReadBit()
+		if err != nil {
+			return out, err
+		}
+		if bit == Zero {
+			code += "0"
+		} else {
+			code += "1"
+		}
+		if r, hasChar := decoderTable[code]; hasChar {
+			out = alphabet.appendSymbol(out, r)
+			code = ""
+			count -= 1
+		}
+	}
+
+	return out, nil
+}
+
+func TestDecodeTable(t *testing.T) {
+	fibonacci := make([]byte, 0)
+	for i, leaf := range fibonacciLeaves(24) {
+		fibonacci = append(fibonacci, bytes.Repeat([]byte{byte('A' + i)}, leaf.freq)...)
+	}
+	// Interleave the symbols so long and short codes alternate in the data
+	for i := 0; i < len(fibonacci); i += 7 {
+		j := len(fibonacci) - 1 - i
+		fibonacci[i], fibonacci[j] = fibonacci[j], fibonacci[i]
+	}
+
+	frequencyTest, err := os.ReadFile("frequency-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := []struct {
+		name     string
+		sample   []byte
+		alphabet Alphabet
+	}{
+		// Codes up to 23 bits need two levels of secondary tables
+		{name: "fibonacci", sample: fibonacci, alphabet: Bytes},
+		{name: "frequency-test", sample: frequencyTest, alphabet: Runes},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			tree, data, count := encodeSample(t, s.sample, s.alphabet, 0)
+
+			decoded, err := decodeWithTable(tree, data, s.alphabet, count)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(s.sample, decoded) {
+				t.Error("Expected the decode table to reproduce the sample")
+			}
+
+			if _, err := decodeWithTable(tree, data[:len(data)-1], s.alphabet, count); err == nil {
+				t.Error("Expected truncated data to fail")
+			}
+		})
+	}
+}
+
+// benchmarkCorpus returns les-mis-test.txt when it is available and the
+// package's own source otherwise
+func benchmarkCorpus(tb testing.TB) []byte {
+	if corpus, err := os.ReadFile("les-mis-test.txt"); err == nil {
+		return corpus
+	}
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	corpus := make([]byte, 0)
+	for _, f := range files {
+		source, err := os.ReadFile(f)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		corpus = append(corpus, source...)
+	}
+
+	return corpus
+}
+
+func BenchmarkDecodeTable(b *testing.B) {
+	corpus := benchmarkCorpus(b)
+	tree, data, count := encodeSample(b, corpus, Runes, 0)
+
+	b.SetBytes(int64(len(corpus)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeWithTable(tree, data, Runes, count); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeBitByBit(b *testing.B) {
+	corpus := benchmarkCorpus(b)
+	tree, data, count := encodeSample(b, corpus, Runes, 0)
+
+	b.SetBytes(int64(len(corpus)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeBitByBit(tree, data, Runes, count); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+)
+
+// DEFLATE (RFC 1951) limits literal/length codes to 15 bits and the codes of
+// the code lengths to 7 bits. The literal/length alphabet has the 256 byte
+// values followed by the end of block symbol; the length symbols after it are
+// never used, since nothing is coded as a back-reference.
+const (
+	deflateMaxCodeLength    = 15
+	deflateMaxCodeLenLength = 7
+	endOfBlock              = 256
+	literalSymbols          = 257
+)
+
+// The code lengths symbols 16 to 18 code runs: 16 repeats the previous length
+// 3 to 6 times and 17 and 18 code 3 to 10 and 11 to 138 zeros
+const (
+	repeatPrevious = 16
+	repeatZero     = 17
+	repeatZeroLong = 18
+)
+
+// codeLengthOrder is the order the lengths of the code lengths code are
+// written in, so that the rarely used ones come last and can be left out
+var codeLengthOrder = [...]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+// DeflateWriter is an io.WriteCloser that codes everything written to it as a
+// raw DEFLATE stream, which compress/flate and zlib decode. The input is split
+// into blocks of up to BlockSize bytes, each written as a dynamic Huffman block
+// with a tree built from its own frequencies, just as a Writer codes a Static
+// block of Bytes. Every byte is coded as a literal: there are no
+// back-references, so the output is only as small as the byte frequencies
+// allow.
+type DeflateWriter struct {
+	// BlockSize is the most bytes of input coded with the same tree; the zero
+	// value is DefaultBlockSize. It must be set before the first call to
+	// Write.
+	BlockSize int
+	w         *bufio.Writer
+	bits      lsbWriter
+	buf       bytes.Buffer
+	closed    bool
+	err       error
+}
+
+// NewDeflateWriter returns a new DeflateWriter. It is the caller's
+// responsibility to call Close on the DeflateWriter when done.
+func NewDeflateWriter(w io.Writer) *DeflateWriter {
+	z := &DeflateWriter{w: bufio.NewWriter(w)}
+	z.bits.w = z.w
+	return z
+}
+
+// Write buffers p and codes every block it completes. The final block is
+// only coded by Close, since DEFLATE marks it in its header.
+func (z *DeflateWriter) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, ErrClosed
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+
+	if z.BlockSize == 0 {
+		z.BlockSize = DefaultBlockSize
+	}
+	if z.BlockSize < 0 {
+		z.err = fmt.Errorf("block size must be positive")
+		return 0, z.err
+	}
+
+	z.buf.Write(p)
+
+	for z.buf.Len() > z.BlockSize {
+		if z.err = z.writeBlock(z.buf.Next(z.BlockSize), false); z.err != nil {
+			return 0, z.err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close codes the remaining input as the final block and flushes the stream
+// to the underlying writer. It does not close the underlying writer.
+func (z *DeflateWriter) Close() error {
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+
+	if z.err != nil {
+		return z.err
+	}
+
+	if err := z.writeBlock(z.buf.Next(z.buf.Len()), true); err != nil {
+		return err
+	}
+
+	if err := z.bits.flush(); err != nil {
+		return err
+	}
+
+	return z.w.Flush()
+}
+
+// writeBlock codes input as a dynamic Huffman block, or an empty input as a
+// fixed Huffman block holding only the end of block symbol. A dynamic block
+// consists of:
+//   - BFINAL, set for the final block, and BTYPE 2 in 3 bits
+//   - HLIT, HDIST and HCLEN, the number of literal/length, distance and code
+//     length code lengths less 257, 1 and 4, in 5, 5 and 4 bits
+//   - the code lengths code's lengths in 3 bits each, in codeLengthOrder
+//   - the literal/length and distance code lengths coded with the code
+//     lengths code (see writeDeflateLengths)
+//   - the code of every byte of input followed by the end of block code
+func (z *DeflateWriter) writeBlock(input []byte, final bool) error {
+	bfinal := uint64(0)
+	if final {
+		bfinal = 1
+	}
+
+	if len(input) == 0 {
+		// The end of block code of the fixed literal/length code is 7 zeros
+		return z.bits.writeBits(bfinal|1<<1, 3+7)
+	}
+
+	ft := NewFrequencyTable(Bytes)
+	if err := ft.Populate(bytes.NewReader(input)); err != nil {
+		return fmt.Errorf("error populating frequency table: %v", err)
+	}
+	ft.table[endOfBlock] = 1
+
+	// The end of block symbol makes for at least two symbols, so every code
+	// has at least one bit. The canonical codes DEFLATE expects are those of
+	// Canonical: shorter codes first, then in order of symbol.
+	tree, err := buildTree(ft, deflateMaxCodeLength)
+	if err != nil {
+		return fmt.Errorf("failed to build tree: %v", err)
+	}
+	codes := tree.ToCodeTable()
+
+	literalLengths := make([]int, literalSymbols)
+	for s, length := range tree.CodeLengths() {
+		literalLengths[s] = length
+	}
+
+	// No distance codes are used, which DEFLATE records as a single distance
+	// code. Giving it a bit rather than none keeps strict decoders happy.
+	distanceLengths := []int{1}
+
+	if err := z.bits.writeBits(bfinal|2<<1, 3); err != nil {
+		return err
+	}
+	if err := writeDeflateLengths(&z.bits, literalLengths, distanceLengths); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
+
+	for _, b := range input {
+		if err := z.bits.writeCode(codes[rune(b)]); err != nil {
+			return fmt.Errorf("failed to write code to output for byte %#x", b)
+		}
+	}
+
+	return z.bits.writeCode(codes[endOfBlock])
+}
+
+// writeDeflateLengths writes the literal/length and distance code lengths.
+// Both are coded as one sequence with a code of its own, whose lengths are
+// written first, along with the counts of each. Runs of a length use the
+// repeat symbols, which may cross from one code's lengths into the other's.
+func writeDeflateLengths(w *lsbWriter, literalLengths, distanceLengths []int) error {
+	lengths := append(append([]int{}, literalLengths...), distanceLengths...)
+
+	// Each run symbol is followed by extra bits, held in the same entry
+	type codeLength struct {
+		symbol, extra, extraBits int
+	}
+
+	var sequence []codeLength
+	for i := 0; i < len(lengths); {
+		length := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == length {
+			run++
+		}
+		i += run
+
+		if length == 0 {
+			for ; run >= 11; run -= minInt(run, 138) {
+				sequence = append(sequence, codeLength{repeatZeroLong, minInt(run, 138) - 11, 7})
+			}
+			if run >= 3 {
+				sequence = append(sequence, codeLength{repeatZero, run - 3, 3})
+				run = 0
+			}
+		} else {
+			sequence = append(sequence, codeLength{symbol: length})
+			for run--; run >= 3; run -= minInt(run, 6) {
+				sequence = append(sequence, codeLength{repeatPrevious, minInt(run, 6) - 3, 2})
+			}
+		}
+		for ; run > 0; run-- {
+			sequence = append(sequence, codeLength{symbol: length})
+		}
+	}
+
+	ft := NewFrequencyTable(Bytes)
+	for _, cl := range sequence {
+		ft.table[rune(cl.symbol)]++
+	}
+
+	tree, err := buildTree(ft, deflateMaxCodeLenLength)
+	if err != nil {
+		return err
+	}
+	clLengths := tree.CodeLengths()
+
+	// A code of a single symbol must still be complete, so it is given a bit
+	// and shares the code with a symbol that is never used
+	if len(clLengths) == 1 {
+		for s := range clLengths {
+			clLengths[s] = 1
+			clLengths[s^1] = 1
+		}
+		if tree, err = NewCanonicalHuffmanTree(clLengths); err != nil {
+			return err
+		}
+	}
+	codes := tree.ToCodeTable()
+
+	hclen := len(codeLengthOrder)
+	for hclen > 4 && clLengths[rune(codeLengthOrder[hclen-1])] == 0 {
+		hclen--
+	}
+
+	counts := [...]struct {
+		value uint64
+		bits  uint
+	}{
+		{uint64(len(literalLengths) - literalSymbols), 5},
+		{uint64(len(distanceLengths) - 1), 5},
+		{uint64(hclen - 4), 4},
+	}
+	for _, count := range counts {
+		if err := w.writeBits(count.value, count.bits); err != nil {
+			return err
+		}
+	}
+	for _, s := range codeLengthOrder[:hclen] {
+		if err := w.writeBits(uint64(clLengths[rune(s)]), 3); err != nil {
+			return err
+		}
+	}
+
+	for _, cl := range sequence {
+		if err := w.writeCode(codes[rune(cl.symbol)]); err != nil {
+			return err
+		}
+		if err := w.writeBits(uint64(cl.extra), uint(cl.extraBits)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// lsbWriter packs bits into bytes starting from the least significant bit, as
+// DEFLATE does, unlike the BitWriter
+type lsbWriter struct {
+	w     io.ByteWriter
+	bits  uint64
+	count uint
+}
+
+// writeBits writes the n least significant bits of v, least significant
+// first, as DEFLATE writes numbers
+func (lw *lsbWriter) writeBits(v uint64, n uint) error {
+	lw.bits |= v << lw.count
+	lw.count += n
+
+	for lw.count >= 8 {
+		if err := lw.w.WriteByte(byte(lw.bits)); err != nil {
+			return err
+		}
+		lw.bits >>= 8
+		lw.count -= 8
+	}
+
+	return nil
+}
+
+// writeCode writes a Huffman code most significant bit first, as DEFLATE
+// writes codes
+func (lw *lsbWriter) writeCode(c Code) error {
+	return lw.writeBits(bits.Reverse64(c.Bits)>>(64-c.Length), c.Length)
+}
+
+// flush writes any partial byte padded with zeros
+func (lw *lsbWriter) flush() error {
+	if lw.count == 0 {
+		return nil
+	}
+	err := lw.w.WriteByte(byte(lw.bits))
+	lw.bits, lw.count = 0, 0
+	return err
+}
+
+// GzipWriter is an io.WriteCloser that wraps the output of a DeflateWriter in
+// the gzip format (RFC 1952), which gunzip and compress/gzip read. The gzip
+// header records no name or modification time, so the output depends only on
+// the input and the BlockSize.
+type GzipWriter struct {
+	*DeflateWriter
+	w           io.Writer
+	wroteHeader bool
+	digest      uint32
+	size        uint32
+}
+
+// NewGzipWriter returns a new GzipWriter. It is the caller's responsibility to
+// call Close on the GzipWriter when done.
+func NewGzipWriter(w io.Writer) *GzipWriter {
+	return &GzipWriter{
+		DeflateWriter: NewDeflateWriter(w),
+		w:             w,
+	}
+}
+
+// Write codes p after writing the gzip header, if it has not been written
+func (z *GzipWriter) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, ErrClosed
+	}
+	if err := z.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	n, err := z.DeflateWriter.Write(p)
+	z.digest = crc32.Update(z.digest, crc32.IEEETable, p[:n])
+	z.size += uint32(n)
+
+	return n, err
+}
+
+// Close ends the DEFLATE stream and writes the gzip trailer: the CRC-32 of the
+// original data and its size modulo 2^32, both little-endian. It does not
+// close the underlying writer.
+func (z *GzipWriter) Close() error {
+	if z.closed {
+		return nil
+	}
+
+	if err := z.writeHeader(); err != nil {
+		z.closed = true
+		return err
+	}
+
+	if err := z.DeflateWriter.Close(); err != nil {
+		return err
+	}
+
+	trailer := [8]byte{}
+	binary.LittleEndian.PutUint32(trailer[:4], z.digest)
+	binary.LittleEndian.PutUint32(trailer[4:], z.size)
+	if _, err := z.w.Write(trailer[:]); err != nil {
+		return fmt.Errorf("failed to write trailer: %v", err)
+	}
+
+	return nil
+}
+
+// writeHeader writes the 10 byte gzip header, once: the magic bytes 1f 8b,
+// the DEFLATE compression method 8, no flags, a modification time of zero,
+// no extra flags and an unknown operating system
+func (z *GzipWriter) writeHeader() error {
+	if z.wroteHeader || z.err != nil {
+		return z.err
+	}
+	z.wroteHeader = true
+
+	if _, z.err = z.w.Write([]byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 255}); z.err != nil {
+		z.err = fmt.Errorf("failed to write header: %v", z.err)
+	}
+
+	return z.err
+}
@@ -0,0 +1,169 @@
+package huffman
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// gzipRoundTrip writes original to a GzipWriter in chunks of chunkSize bytes
+// and reads it back with compress/gzip
+func gzipRoundTrip(t *testing.T, original []byte, blockSize, chunkSize int) ([]byte, []byte) {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	writer := NewGzipWriter(&buf)
+	writer.BlockSize = blockSize
+	for p := original; len(p) > 0; {
+		n := chunkSize
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := writer.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressed := append([]byte{}, buf.Bytes()...)
+
+	reader, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return compressed, decompressed
+}
+
+func TestGzipWriter(t *testing.T) {
+	binary := make([]byte, 5000)
+	rand.New(rand.NewSource(1)).Read(binary)
+
+	inputs := map[string][]byte{
+		"empty":      {},
+		"single NUL": {0},
+		"one symbol": bytes.Repeat([]byte{'a'}, 1000),
+		"every byte": func() []byte {
+			p := make([]byte, 256)
+			for i := range p {
+				p[i] = byte(i)
+			}
+			return p
+		}(),
+		"binary": binary,
+		"text":   []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 100)),
+	}
+
+	for name, original := range inputs {
+		for _, blockSize := range []int{0, 1, 100} {
+			_, decompressed := gzipRoundTrip(t, original, blockSize, 37)
+			if !bytes.Equal(original, decompressed) {
+				t.Errorf("Expected %s with a block size of %d to decompress to the original", name, blockSize)
+			}
+		}
+	}
+}
+
+func TestGzipWriterCorpus(t *testing.T) {
+	corpus := benchmarkCorpus(t)
+
+	compressed, decompressed := gzipRoundTrip(t, corpus, 16<<10, len(corpus))
+	if !bytes.Equal(corpus, decompressed) {
+		t.Fatal("Expected the corpus to decompress to the original")
+	}
+
+	// Literal codes save as much as static codes of Bytes, give or take the
+	// size of the trees
+	static := compress(t, corpus, Header{Alphabet: Bytes, BlockSize: 16 << 10})
+	t.Logf("%d bytes coded in %d bytes of gzip and %d bytes with static codes", len(corpus), len(compressed), len(static))
+
+	if len(compressed) > len(static)*11/10 {
+		t.Errorf("Expected gzip output close to the %d bytes of static codes but received %d bytes", len(static), len(compressed))
+	}
+
+	// Nothing in the header depends on when or where the output was written
+	again, _ := gzipRoundTrip(t, corpus, 16<<10, 1000)
+	if !bytes.Equal(compressed, again) {
+		t.Error("Expected every compression to give the same output")
+	}
+	if mtime := compressed[4:8]; !bytes.Equal(mtime, []byte{0, 0, 0, 0}) {
+		t.Errorf("Expected no modification time but received %x", mtime)
+	}
+}
+
+func TestDeflateWriter(t *testing.T) {
+	original := []byte(strings.Repeat("abracadabra ", 500))
+
+	buf := bytes.Buffer{}
+	writer := NewDeflateWriter(&buf)
+	writer.BlockSize = 1000
+	if _, err := writer.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := writer.Write(original); err != ErrClosed {
+		t.Errorf("Expected %v but received %v", ErrClosed, err)
+	}
+
+	decompressed, err := io.ReadAll(flate.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(original, decompressed) {
+		t.Error("Expected the DEFLATE stream to decompress to the original")
+	}
+}
+
+func TestDeflateLengths(t *testing.T) {
+	// Long runs of zeros and of other lengths are coded with the repeat
+	// symbols
+	literalLengths := make([]int, literalSymbols)
+	for i := 0; i < 8; i++ {
+		literalLengths['a'+i] = 4
+	}
+	literalLengths[endOfBlock] = 1
+
+	buf := bytes.Buffer{}
+	w := lsbWriter{w: &buf}
+	if err := writeDeflateLengths(&w, literalLengths, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 258 code lengths could never fit in 20 bytes without the repeat
+	// symbols
+	if buf.Len() > 20 {
+		t.Errorf("Expected the code lengths to fit in 20 bytes but received %d", buf.Len())
+	}
+
+	// Check the header by decoding a block built from it with compress/flate
+	block := bytes.Buffer{}
+	w = lsbWriter{w: &block}
+	w.writeBits(1|2<<1, 3)
+	writeDeflateLengths(&w, literalLengths, []int{1})
+	w.writeBits(0, 1) // the end of block code
+	w.flush()
+
+	decompressed, err := io.ReadAll(flate.NewReader(&block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decompressed) != 0 {
+		t.Errorf("Expected an empty block but received %q", decompressed)
+	}
+}
@@ -0,0 +1,139 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// escapeSymbol stands for every symbol that a code trained on other data has
+// no code for. It lies beyond the escaped bytes, so it is never a symbol of
+// the input itself.
+const escapeSymbol = escapeBase + 0x100
+
+// Evaluation describes how well a code trained on one FrequencyTable codes the
+// symbols counted by another. A symbol that did not occur in training is coded
+// as the code of an escape, which is trained with a count of one, followed by
+// the symbol itself in a fixed number of bits (8 for bytes and 21 for
+// characters).
+type Evaluation struct {
+	// Total is the number of symbols coded
+	Total int `json:"total"`
+	// CrossEntropy is the average length in bits per symbol of the ideal code
+	// for the training frequencies, escapes included
+	CrossEntropy float64 `json:"cross_entropy"`
+	// Escapes is the number of symbols that did not occur in training and
+	// Unseen the number of distinct such symbols
+	Escapes int `json:"escapes"`
+	Unseen  int `json:"unseen"`
+	// Size is the length in bytes of the data coded with the trained tree
+	Size uint64 `json:"size"`
+	// OptimalSize is the length in bytes of the data coded with a tree built
+	// from its own frequencies, which has to be sent along in a header of
+	// OptimalHeaderSize bytes
+	OptimalSize       uint64 `json:"optimal_size"`
+	OptimalHeaderSize uint64 `json:"optimal_header_size"`
+	// Penalty is how much larger Size is than OptimalSize and its header, as
+	// a fraction of the latter. It is negative where a shared code saves more
+	// header than it costs in data.
+	Penalty float64 `json:"penalty"`
+}
+
+// TrainedCode is the code for the frequencies of training data along with an
+// escape for the symbols that the training data lacks
+type TrainedCode struct {
+	table         *FrequencyTable
+	tree          *HuffmanTree
+	codes         map[rune]string
+	maxCodeLength int
+}
+
+// NewTrainedCode builds the canonical tree, limited to maxCodeLength bits (0
+// for no limit), for the frequencies in ft and an escape
+func NewTrainedCode(ft *FrequencyTable, maxCodeLength int) (*TrainedCode, error) {
+	table := NewFrequencyTable(ft.alphabet)
+	for s, count := range ft.table {
+		table.table[s] = count
+	}
+	table.table[escapeSymbol] = 1
+
+	tree, err := buildTree(table, maxCodeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrainedCode{
+		table:         table,
+		tree:          tree,
+		codes:         tree.ToLookupTable(),
+		maxCodeLength: maxCodeLength,
+	}, nil
+}
+
+// Tree returns the trained tree, in which the escape is the symbol beyond the
+// last escaped byte
+func (tc *TrainedCode) Tree() *HuffmanTree {
+	return tc.tree
+}
+
+// Evaluate codes the symbols counted by ft, which must share the alphabet of
+// the training data, and compares the result with ft's own optimal tree under
+// the same limit on code lengths
+func (tc *TrainedCode) Evaluate(ft *FrequencyTable) (*Evaluation, error) {
+	if ft.alphabet != tc.table.alphabet {
+		return nil, fmt.Errorf("cannot code %v with a code trained on %v", ft.alphabet, tc.table.alphabet)
+	}
+
+	// The ideal code for the training frequencies gives each symbol the
+	// information content of its probability in training
+	total := float64(tc.table.Total())
+	information := func(s rune) float64 {
+		return -math.Log2(float64(tc.table.table[s]) / total)
+	}
+	symbolBits := ft.alphabet.symbolBits()
+
+	evaluation := &Evaluation{Total: ft.Total()}
+
+	var bits uint64
+	var entropy float64
+	for s, count := range ft.table {
+		if code, hasCode := tc.codes[s]; hasCode {
+			bits += uint64(count * len(code))
+			entropy += float64(count) * information(s)
+			continue
+		}
+
+		evaluation.Escapes += count
+		evaluation.Unseen += 1
+		bits += uint64(count) * (uint64(len(tc.codes[escapeSymbol])) + uint64(symbolBits))
+		entropy += float64(count) * (information(escapeSymbol) + float64(symbolBits))
+	}
+	evaluation.Size = (bits + 7) / 8
+
+	if evaluation.Total == 0 {
+		return evaluation, nil
+	}
+	evaluation.CrossEntropy = entropy / float64(evaluation.Total)
+
+	own, err := buildTree(ft, tc.maxCodeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	var optimalBits uint64
+	for s, code := range own.ToLookupTable() {
+		optimalBits += uint64(ft.table[s] * len(code))
+	}
+	evaluation.OptimalSize = (optimalBits + 7) / 8
+
+	header := bytes.Buffer{}
+	if err := own.WriteHeader(NewBitWriter(&header), ft.alphabet); err != nil {
+		return nil, err
+	}
+	evaluation.OptimalHeaderSize = uint64(header.Len())
+
+	optimal := evaluation.OptimalSize + evaluation.OptimalHeaderSize
+	evaluation.Penalty = float64(evaluation.Size)/float64(optimal) - 1
+
+	return evaluation, nil
+}
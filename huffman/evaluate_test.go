@@ -0,0 +1,76 @@
+package huffman
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	training, err := os.ReadFile("frequency-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(p []byte) *FrequencyTable {
+		ft := NewFrequencyTable(Runes)
+		if err := ft.Populate(bytes.NewReader(p)); err != nil {
+			t.Fatal(err)
+		}
+		return ft
+	}
+
+	trained, err := NewTrainedCode(count(training), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Coding the training data itself needs no escapes, takes as many bits as
+	// encoding it with the trained tree and cannot beat the entropy
+	evaluation, err := trained.Evaluate(count(training))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := count(training).Stats(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Buffer{}
+	writer := NewBitWriter(&data)
+	if _, err := encode(writer, training, Runes, trained.Tree().ToCodeTable(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(Zero); err != nil {
+		t.Fatal(err)
+	}
+
+	if evaluation.Escapes != 0 || evaluation.Unseen != 0 {
+		t.Errorf("Expected no escapes for the training data but received %d", evaluation.Escapes)
+	}
+	if evaluation.Size != uint64(data.Len()) {
+		t.Errorf("Expected a size of %d bytes but received %d", data.Len(), evaluation.Size)
+	}
+	if evaluation.CrossEntropy < stats.Entropy {
+		t.Errorf("Expected a cross-entropy of at least %f but received %f", stats.Entropy, evaluation.CrossEntropy)
+	}
+	if evaluation.OptimalSize > evaluation.Size {
+		t.Errorf("Expected the optimal size of %d bytes to be no larger than %d", evaluation.OptimalSize, evaluation.Size)
+	}
+
+	// Each occurrence of a symbol missing from training is escaped
+	evaluation, err = trained.Evaluate(count([]byte("abc xyz xyz")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evaluation.Escapes != 8 || evaluation.Unseen != 4 {
+		t.Errorf("Expected 8 escapes of 4 symbols but received %d of %d", evaluation.Escapes, evaluation.Unseen)
+	}
+	if evaluation.Penalty <= 0 {
+		t.Errorf("Expected a penalty for escaping most symbols but received %f", evaluation.Penalty)
+	}
+
+	if _, err := trained.Evaluate(NewFrequencyTable(Bytes)); err == nil {
+		t.Error("Expected an error for a table of another alphabet")
+	}
+}
@@ -0,0 +1,158 @@
+package huffman
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+func NewFrequencyTable(alphabet Alphabet) *FrequencyTable {
+	return &FrequencyTable{
+		alphabet: alphabet,
+		table:    make(map[rune]int),
+	}
+}
+
+// NewContextFrequencyTable returns a FrequencyTable that also counts every
+// symbol under the symbol preceding it (its order-1 context)
+func NewContextFrequencyTable(alphabet Alphabet) *FrequencyTable {
+	ft := NewFrequencyTable(alphabet)
+	ft.contexts = make(map[rune]*FrequencyTable)
+	return ft
+}
+
+// NewBlockFrequencyTable returns a FrequencyTable that also counts the symbols
+// of every block a Writer with the given BlockSize (0 for DefaultBlockSize)
+// would code, so that its Stats predict the Writer's output
+func NewBlockFrequencyTable(alphabet Alphabet, blockSize int) *FrequencyTable {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	ft := NewFrequencyTable(alphabet)
+	ft.blockSize = blockSize
+	return ft
+}
+
+type FrequencyTable struct {
+	alphabet Alphabet
+	table    map[rune]int
+	// contexts holds the counts of the symbols following each symbol, if the
+	// table counts them. previous is the last symbol counted, which is the
+	// context of the next symbol once there is one.
+	contexts    map[rune]*FrequencyTable
+	previous    rune
+	hasPrevious bool
+	// blocks holds the counts of each block of at most blockSize bytes, if
+	// the table counts them. blockBytes is the size of the last block.
+	blockSize  int
+	blocks     []*FrequencyTable
+	blockBytes int
+}
+
+// Populate counts every symbol of the table's alphabet read from r until
+// io.EOF. Tables from NewContextFrequencyTable count the symbols under their
+// contexts too, continuing from the last symbol counted by earlier calls, and
+// tables from NewBlockFrequencyTable count them in their blocks.
+func (ft *FrequencyTable) Populate(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(ft.alphabet.split())
+
+	for scanner.Scan() {
+		r, size := ft.alphabet.nextSymbol(scanner.Bytes())
+		ft.table[r] += 1
+
+		if ft.blockSize > 0 {
+			ft.countInBlock(r, size)
+		}
+
+		if ft.contexts != nil {
+			if ft.hasPrevious {
+				context, ok := ft.contexts[ft.previous]
+				if !ok {
+					context = NewFrequencyTable(ft.alphabet)
+					ft.contexts[ft.previous] = context
+				}
+				context.table[r] += 1
+			}
+			ft.previous, ft.hasPrevious = r, true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to scan input: %v", err)
+	}
+
+	return nil
+}
+
+// countInBlock counts the symbol r of size bytes in the last block, starting a
+// new block if it would not fit. A Writer splits its input the same way, at
+// the last symbol boundary within BlockSize bytes.
+func (ft *FrequencyTable) countInBlock(r rune, size int) {
+	if len(ft.blocks) == 0 || (ft.blockBytes > 0 && ft.blockBytes+size > ft.blockSize) {
+		ft.blocks = append(ft.blocks, NewFrequencyTable(ft.alphabet))
+		ft.blockBytes = 0
+	}
+	ft.blocks[len(ft.blocks)-1].table[r] += 1
+	ft.blockBytes += size
+}
+
+func (ft *FrequencyTable) Log(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 1, 1, 1, ' ', 0)
+
+	count := 0
+	for r, freq := range ft.table {
+		count += 1
+		fmt.Fprintf(writer, "%d.\t%q\t%d\n", count, r, freq)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush tab writer: %v", err)
+	}
+
+	return nil
+}
+
+func (ft *FrequencyTable) Get(r rune) int {
+	return ft.table[r]
+}
+
+// Context returns the counts of the symbols that followed s, or nil if nothing
+// did or the table does not count contexts
+func (ft *FrequencyTable) Context(s rune) *FrequencyTable {
+	return ft.contexts[s]
+}
+
+// Contexts returns every symbol that another symbol followed, in increasing
+// order
+func (ft *FrequencyTable) Contexts() []rune {
+	contexts := make([]rune, 0, len(ft.contexts))
+	for s := range ft.contexts {
+		contexts = append(contexts, s)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i] < contexts[j]
+	})
+	return contexts
+}
+
+// Total returns the number of symbols counted
+func (ft *FrequencyTable) Total() int {
+	total := 0
+	for _, freq := range ft.table {
+		total += freq
+	}
+	return total
+}
+
+func (ft *FrequencyTable) ToList() []*FrequencyNode {
+	list := make([]*FrequencyNode, 0)
+
+	for char, freq := range ft.table {
+		list = append(list, &FrequencyNode{char: char, freq: freq})
+	}
+
+	return list
+}
@@ -0,0 +1,129 @@
+package huffman
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFrequencyTable(t *testing.T) {
+	type testCase struct {
+		char         string
+		expectedFreq int
+	}
+
+	tests := []testCase{
+		{
+			char:         "a",
+			expectedFreq: 10,
+		},
+		{
+			char:         "b",
+			expectedFreq: 9,
+		},
+		{
+			char:         "c",
+			expectedFreq: 8,
+		},
+		{
+			char:         "ę",
+			expectedFreq: 6,
+		},
+		{
+			char:         "i",
+			expectedFreq: 2,
+		},
+		{
+			char:         "Ü",
+			expectedFreq: 6,
+		},
+		{
+			char:         "Š",
+			expectedFreq: 4,
+		},
+		{
+			char:         "Z",
+			expectedFreq: 11,
+		},
+		{
+			char:         "k",
+			expectedFreq: 0,
+		},
+		{
+			char:         "m",
+			expectedFreq: 0,
+		},
+	}
+
+	f, err := os.Open("frequency-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ft := NewFrequencyTable(Runes)
+
+	if err := ft.Populate(f); err != nil {
+		t.Errorf("failed to populate table: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.char, func(t *testing.T) {
+			r, _ := utf8.DecodeRuneInString(tt.char)
+			actualCount := ft.Get(r)
+
+			if actualCount != tt.expectedFreq {
+				t.Errorf("expected count for %s to be %d, but received %d", tt.char, tt.expectedFreq, actualCount)
+			}
+		})
+	}
+}
+
+func TestContextFrequencyTable(t *testing.T) {
+	ft := NewContextFrequencyTable(Runes)
+
+	// Counting continues from the last symbol of the previous call
+	for _, input := range []string{"quiqu", "e qua"} {
+		if err := ft.Populate(strings.NewReader(input)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ft.Get('q') != 3 || ft.Total() != 10 {
+		t.Errorf("Expected order-0 counts to be unaffected by contexts")
+	}
+
+	if contexts := ft.Contexts(); !reflect.DeepEqual(contexts, []rune(" eiqu")) {
+		t.Errorf("Expected contexts %q but received %q", " eiqu", string(contexts))
+	}
+
+	expected := map[rune]map[rune]int{
+		' ': {'q': 1},
+		'e': {' ': 1},
+		'i': {'q': 1},
+		'q': {'u': 3},
+		'u': {'i': 1, 'e': 1, 'a': 1},
+	}
+	for context, counts := range expected {
+		total := 0
+		for s, count := range counts {
+			if actual := ft.Context(context).Get(s); actual != count {
+				t.Errorf("Expected %q to follow %q %d times but received %d", s, context, count, actual)
+			}
+			total += count
+		}
+		if ft.Context(context).Total() != total {
+			t.Errorf("Expected only %v to follow %q", counts, context)
+		}
+	}
+
+	if ft.Context('a') != nil {
+		t.Error("Expected no context for the final symbol")
+	}
+
+	if NewFrequencyTable(Runes).Context('q') != nil {
+		t.Error("Expected tables from NewFrequencyTable not to count contexts")
+	}
+}
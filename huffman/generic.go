@@ -0,0 +1,177 @@
+package huffman
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Codebook is a canonical Huffman code for symbols of any comparable type:
+// bytes, runes, words, integers or structs such as the enum-like fields of a
+// binary record. It is built from frequencies by NewCodebook, or rebuilt from
+// the code lengths of another Codebook by NewCodebookFromLengths, and codes one
+// symbol at a time with a BitWriter or BitReader.
+//
+// Canonical codes are assigned in order of code length and then of the less
+// function the Codebook is built with, which must be a strict total order on
+// the symbols. Two Codebooks built from the same lengths and order therefore
+// agree on every code, so only the lengths need to be shared.
+type Codebook[S comparable] struct {
+	// symbols holds the symbols in the order of less. The codes are built
+	// for their positions in it, which are runes like every other code.
+	symbols []S
+	index   map[S]int
+	codes   []Code
+	table   *decodeTable
+}
+
+// NewCodebook builds the Huffman code for the given frequencies, leaving out
+// symbols with a frequency of zero. Ties between equal frequencies are broken
+// by less, so the code depends only on the frequencies. Codes are at most
+// limit bits long unless it is zero, as for Header.MaxCodeLength. The code of
+// a single symbol is empty.
+func NewCodebook[S comparable](frequencies map[S]int, less func(a, b S) bool, limit int) (*Codebook[S], error) {
+	if limit < 0 || limit > maxCodeLength {
+		return nil, fmt.Errorf("maximum code length must be between 0 (unlimited) and %d bits", maxCodeLength)
+	}
+
+	symbols := make([]S, 0, len(frequencies))
+	for s, freq := range frequencies {
+		if freq < 0 {
+			return nil, fmt.Errorf("symbol %v has a negative frequency", s)
+		}
+		if freq > 0 {
+			symbols = append(symbols, s)
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("no symbols to code")
+	}
+	if err := sortSymbols(symbols, less); err != nil {
+		return nil, err
+	}
+
+	ft := NewFrequencyTable(Runes)
+	for i, s := range symbols {
+		ft.table[rune(i)] = frequencies[s]
+	}
+
+	tree, err := buildTree(ft, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCodebook(symbols, tree.CodeLengths())
+}
+
+// NewCodebookFromLengths rebuilds the Codebook with the given code lengths,
+// such as those of Codebook.Lengths, and less. An error is returned if the
+// lengths describe more codes than fit.
+func NewCodebookFromLengths[S comparable](lengths map[S]int, less func(a, b S) bool) (*Codebook[S], error) {
+	symbols := make([]S, 0, len(lengths))
+	for s, length := range lengths {
+		if length < 0 || length > maxCodeLength {
+			return nil, fmt.Errorf("code length of %v must be between 0 and %d bits", s, maxCodeLength)
+		}
+		symbols = append(symbols, s)
+	}
+	if err := sortSymbols(symbols, less); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[rune]int, len(symbols))
+	for i, s := range symbols {
+		positions[rune(i)] = lengths[s]
+	}
+
+	return newCodebook(symbols, positions)
+}
+
+// sortSymbols sorts symbols by less, verifying that it tells them apart. If it
+// did not, the order of the symbols it ties would follow the map they came
+// from, and so would their codes.
+func sortSymbols[S comparable](symbols []S, less func(a, b S) bool) error {
+	sort.Slice(symbols, func(i, j int) bool {
+		return less(symbols[i], symbols[j])
+	})
+
+	for i := 1; i < len(symbols); i++ {
+		if !less(symbols[i-1], symbols[i]) {
+			return fmt.Errorf("symbols %v and %v are not ordered by less", symbols[i-1], symbols[i])
+		}
+	}
+
+	return nil
+}
+
+// newCodebook builds the Codebook of symbols, sorted by less, from the code
+// lengths of their positions
+func newCodebook[S comparable](symbols []S, lengths map[rune]int) (*Codebook[S], error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("no symbols to code")
+	}
+
+	tree, err := NewCanonicalHuffmanTree(lengths)
+	if err != nil {
+		return nil, err
+	}
+
+	cb := &Codebook[S]{
+		symbols: symbols,
+		index:   make(map[S]int, len(symbols)),
+		codes:   make([]Code, len(symbols)),
+		table:   newDecodeTable(tree),
+	}
+
+	for position, code := range tree.ToCodeTable() {
+		cb.codes[position] = code
+	}
+	for i, s := range symbols {
+		cb.index[s] = i
+	}
+
+	return cb, nil
+}
+
+// Len returns the number of symbols with a code
+func (cb *Codebook[S]) Len() int {
+	return len(cb.symbols)
+}
+
+// Code returns the code of s, if it has one
+func (cb *Codebook[S]) Code(s S) (Code, bool) {
+	i, ok := cb.index[s]
+	if !ok {
+		return Code{}, false
+	}
+	return cb.codes[i], true
+}
+
+// Lengths returns the length in bits of every symbol's code, from which
+// NewCodebookFromLengths rebuilds the Codebook
+func (cb *Codebook[S]) Lengths() map[S]int {
+	lengths := make(map[S]int, len(cb.symbols))
+	for i, s := range cb.symbols {
+		lengths[s] = int(cb.codes[i].Length)
+	}
+	return lengths
+}
+
+// Encode writes the code of s
+func (cb *Codebook[S]) Encode(w *BitWriter, s S) error {
+	code, ok := cb.Code(s)
+	if !ok {
+		return fmt.Errorf("huffman: symbol %v has no code", s)
+	}
+	return w.WriteBits(code.Bits, code.Length)
+}
+
+// Decode reads the code of the next symbol and returns the symbol.
+// ErrInvalidCode is returned for bits that begin no code.
+func (cb *Codebook[S]) Decode(r *BitReader) (S, error) {
+	position, err := cb.table.decodeSymbol(r)
+	if err != nil {
+		var zero S
+		return zero, err
+	}
+	return cb.symbols[position], nil
+}
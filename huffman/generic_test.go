@@ -0,0 +1,195 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripCodebook codes every symbol with cb and decodes them again
+func roundTripCodebook[S comparable](t *testing.T, cb *Codebook[S], symbols []S) []S {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	w := NewBitWriter(&buf)
+	for _, s := range symbols {
+		if err := cb.Encode(w, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(Zero); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewBitReader(&buf)
+	decoded := make([]S, 0, len(symbols))
+	for range symbols {
+		s, err := cb.Decode(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, s)
+	}
+
+	return decoded
+}
+
+func TestCodebookWords(t *testing.T) {
+	words := strings.Fields(strings.Repeat("the cat and the dog and the bird saw the cat ", 20) + "zebra")
+
+	frequencies := make(map[string]int)
+	for _, word := range words {
+		frequencies[word] += 1
+	}
+
+	less := func(a, b string) bool { return a < b }
+
+	cb, err := NewCodebook(frequencies, less, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cb.Len() != 7 {
+		t.Errorf("Expected 7 words but received %d", cb.Len())
+	}
+
+	the, _ := cb.Code("the")
+	zebra, _ := cb.Code("zebra")
+	if the.Length >= zebra.Length {
+		t.Errorf("Expected the most frequent word to have a shorter code than the rarest, but received %d and %d bits", the.Length, zebra.Length)
+	}
+
+	if decoded := roundTripCodebook(t, cb, words); !reflect.DeepEqual(words, decoded) {
+		t.Errorf("Expected %v but received %v", words, decoded)
+	}
+
+	// The lengths and order are all another Codebook needs to agree on every
+	// code
+	rebuilt, err := NewCodebookFromLengths(cb.Lengths(), less)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for word := range frequencies {
+		expected, _ := cb.Code(word)
+		if code, ok := rebuilt.Code(word); !ok || code != expected {
+			t.Errorf("Expected %q to have the code %v but received %v", word, expected, code)
+		}
+	}
+
+	if err := cb.Encode(NewBitWriter(io.Discard), "unicorn"); err == nil {
+		t.Error("Expected an error for a word without a code")
+	}
+}
+
+func TestCodebookRecords(t *testing.T) {
+	type field struct {
+		kind  uint8
+		width int
+	}
+
+	less := func(a, b field) bool {
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.width < b.width
+	}
+
+	frequencies := map[field]int{
+		{kind: 1, width: 4}: 50,
+		{kind: 1, width: 8}: 30,
+		{kind: 2, width: 4}: 10,
+		{kind: 3, width: 0}: 5,
+		{kind: 3, width: 1}: 5,
+		{kind: 4, width: 2}: 0,
+	}
+
+	cb, err := NewCodebook(frequencies, less, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cb.Code(field{kind: 4, width: 2}); ok {
+		t.Error("Expected no code for a symbol with a frequency of zero")
+	}
+
+	records := []field{{1, 4}, {3, 1}, {1, 8}, {2, 4}, {3, 0}, {1, 4}}
+	if decoded := roundTripCodebook(t, cb, records); !reflect.DeepEqual(records, decoded) {
+		t.Errorf("Expected %v but received %v", records, decoded)
+	}
+
+	// Ties are broken by less rather than by the order of the map, so every
+	// build gives the same codes
+	for i := 0; i < 10; i++ {
+		again, err := NewCodebook(frequencies, less, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cb, again) {
+			t.Fatal("Expected every build to give the same codes")
+		}
+	}
+}
+
+func TestCodebookIntegers(t *testing.T) {
+	frequencies := make(map[int]int)
+	for i := 0; i < 300; i++ {
+		frequencies[i*i] = 1 + i%17
+	}
+	less := func(a, b int) bool { return a < b }
+
+	cb, err := NewCodebook(frequencies, less, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s, length := range cb.Lengths() {
+		if length > 10 {
+			t.Errorf("Expected codes of at most 10 bits but %d has %d", s, length)
+		}
+	}
+
+	symbols := []int{0, 1, 4, 89401, 144, 0}
+	if decoded := roundTripCodebook(t, cb, symbols); !reflect.DeepEqual(symbols, decoded) {
+		t.Errorf("Expected %v but received %v", symbols, decoded)
+	}
+
+	// A single symbol has an empty code
+	single, err := NewCodebook(map[int]int{42: 7}, less, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := single.Code(42); code.Length != 0 {
+		t.Errorf("Expected an empty code for a single symbol but received %d bits", code.Length)
+	}
+	if decoded := roundTripCodebook(t, single, []int{42, 42, 42}); !reflect.DeepEqual(decoded, []int{42, 42, 42}) {
+		t.Errorf("Expected %v but received %v", []int{42, 42, 42}, decoded)
+	}
+}
+
+func TestInvalidGenericCodebook(t *testing.T) {
+	less := func(a, b byte) bool { return a < b }
+
+	if _, err := NewCodebook(map[byte]int{}, less, 0); err == nil {
+		t.Error("Expected an error without symbols")
+	}
+	if _, err := NewCodebook(map[byte]int{'a': 1, 'b': -1}, less, 0); err == nil {
+		t.Error("Expected an error for a negative frequency")
+	}
+	if _, err := NewCodebook(map[byte]int{'a': 1, 'b': 1}, func(a, b byte) bool { return false }, 0); err == nil {
+		t.Error("Expected an error for an order that does not tell symbols apart")
+	}
+	if _, err := NewCodebookFromLengths(map[byte]int{'a': 1, 'b': 1, 'c': 1}, less); err == nil {
+		t.Error("Expected an error for over-subscribed code lengths")
+	}
+
+	// Incomplete codes leave bits that begin no code
+	cb, err := NewCodebookFromLengths(map[byte]int{'a': 1, 'b': 2}, less)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cb.Decode(NewBitReader(bytes.NewReader([]byte{0xff}))); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Expected %v but received %v", ErrInvalidCode, err)
+	}
+}
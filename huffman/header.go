@@ -0,0 +1,291 @@
+package huffman
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A stream begins with the magic bytes followed by the format version. The
+// first magic byte has its most significant bit set, which the pre-order tree
+// of headerless streams never begins with.
+//
+// Version 1 streams code the whole input as a single block and record its size
+// in the header. Version 2 streams split the input into blocks with their own
+// trees and record the size in the trailer, so they can be written without
+// holding the whole input.
+const (
+	magic         = "\x89HUF"
+	formatVersion = 2
+)
+
+// DefaultBlockSize is the block size used when a Header's BlockSize is zero
+const DefaultBlockSize = 1 << 20
+
+var (
+	ErrHeader  = errors.New("huffman: invalid header")
+	ErrVersion = errors.New("huffman: unsupported format version")
+)
+
+// Coding identifies how the codes of a stream are derived
+type Coding uint8
+
+const (
+	// Static codes are canonical codes built from the frequencies of each
+	// block and stored as code lengths ahead of its data
+	Static Coding = iota
+	// Adaptive codes come from an AdaptiveHuffmanTree that is updated after
+	// every symbol, so nothing precedes the data but a block is coded in a
+	// single pass
+	Adaptive
+	// Shared codes come from a SharedCodebook identified in the header, so
+	// blocks carry no tree at all
+	Shared
+	// Context codes are static codes with a tree per preceding symbol (an
+	// order-1 context), for the contexts that pay for one, and an order-0
+	// tree for the rest
+	Context
+)
+
+func (c Coding) String() string {
+	switch c {
+	case Static:
+		return "static"
+	case Adaptive:
+		return "adaptive"
+	case Shared:
+		return "shared"
+	case Context:
+		return "context"
+	default:
+		return fmt.Sprintf("Coding(%d)", uint8(c))
+	}
+}
+
+func (c Coding) valid() bool {
+	return c == Static || c == Adaptive || c == Shared || c == Context
+}
+
+// resumable reports whether a block can be decoded from a checkpoint partway
+// through it, which codes that depend on the preceding symbols cannot be
+func (c Coding) resumable() bool {
+	return c == Static || c == Shared
+}
+
+// Checksum identifies the checksum of the original data stored in the trailer
+type Checksum uint8
+
+const (
+	// CRC32 is the IEEE CRC-32 used by gzip, stored big-endian
+	CRC32 Checksum = iota
+	// NoChecksum omits the trailer
+	NoChecksum
+)
+
+func (c Checksum) String() string {
+	switch c {
+	case CRC32:
+		return "crc32"
+	case NoChecksum:
+		return "none"
+	default:
+		return fmt.Sprintf("Checksum(%d)", uint8(c))
+	}
+}
+
+func (c Checksum) valid() bool {
+	return c == CRC32 || c == NoChecksum
+}
+
+// The feature flags byte packs the alphabet, coding and checksum. The most
+// significant bit marks an index after the trailer; it is reserved and must be
+// zero in version 1 streams.
+const (
+	alphabetShift = 0
+	alphabetMask  = 0x03
+	codingShift   = 2
+	codingMask    = 0x07
+	checksumShift = 5
+	checksumMask  = 0x03
+	indexFlag     = 0x80
+)
+
+// Header holds the settings a stream is encoded with. They are recorded ahead
+// of the coded data, so the fields of a Writer's Header must be set before the
+// first call to Write, while a Reader's Header is populated by NewReader.
+//
+// The header is laid out as:
+//   - the 4 magic bytes "\x89HUF"
+//   - the format version byte
+//   - the feature flags byte
+//   - the maximum code length byte
+//   - the block size as a uvarint (the size of the original data in version 1)
+//   - the codebook ID as 4 bytes, big-endian, for Shared codes
+type Header struct {
+	// Alphabet selects the symbols that receive codes; the zero value codes
+	// UTF-8 characters
+	Alphabet Alphabet
+	// Coding selects how codes are derived; the zero value is Static
+	Coding Coding
+	// Checksum selects the checksum verified by the Reader; the zero value is
+	// CRC32
+	Checksum Checksum
+	// MaxCodeLength caps the length of every code in bits, which lets decoders
+	// rely on fixed-width bit buffers and tables; the zero value leaves codes
+	// unrestricted. Context codes limit every tree of a block. Adaptive codes
+	// cannot be limited, and shared codes are limited when the codebook is
+	// built instead.
+	MaxCodeLength int
+	// BlockSize is the most bytes of the original data coded with the same
+	// tree. Smaller blocks adapt to changing statistics at the cost of more
+	// trees; the zero value is DefaultBlockSize, which a Writer fills in. It
+	// is zero for version 1 streams, which are a single block.
+	BlockSize int
+	// Indexed appends an index of the blocks to the stream, which lets a
+	// BlockReader decode them concurrently
+	Indexed bool
+	// Codebook is the ID of the SharedCodebook that Shared codes come from. A
+	// Writer fills it in from its SharedCodebook.
+	Codebook uint32
+	// Size is the length of the original data in bytes. A Writer fills it in
+	// when it is closed and a Reader once it reaches the end of the stream
+	// (or immediately for version 1 streams).
+	Size uint64
+}
+
+func (h *Header) flags() (byte, error) {
+	if !h.Alphabet.valid() {
+		return 0, fmt.Errorf("unsupported alphabet %v", h.Alphabet)
+	}
+	if !h.Coding.valid() {
+		return 0, fmt.Errorf("unsupported coding %v", h.Coding)
+	}
+	if !h.Checksum.valid() {
+		return 0, fmt.Errorf("unsupported checksum %v", h.Checksum)
+	}
+
+	flags := byte(h.Alphabet)<<alphabetShift | byte(h.Coding)<<codingShift | byte(h.Checksum)<<checksumShift
+	if h.Indexed {
+		flags |= indexFlag
+	}
+
+	return flags, nil
+}
+
+func (h *Header) setFlags(flags byte) error {
+	h.Indexed = flags&indexFlag != 0
+	h.Alphabet = Alphabet(flags >> alphabetShift & alphabetMask)
+	h.Coding = Coding(flags >> codingShift & codingMask)
+	h.Checksum = Checksum(flags >> checksumShift & checksumMask)
+
+	if !h.Alphabet.valid() {
+		return fmt.Errorf("unsupported alphabet %v", h.Alphabet)
+	}
+	if !h.Coding.valid() {
+		return fmt.Errorf("unsupported coding %v", h.Coding)
+	}
+	if !h.Checksum.valid() {
+		return fmt.Errorf("unsupported checksum %v", h.Checksum)
+	}
+
+	return nil
+}
+
+func (h *Header) write(w *bufio.Writer) error {
+	flags, err := h.flags()
+	if err != nil {
+		return err
+	}
+	if h.MaxCodeLength < 0 || h.MaxCodeLength > maxCodeLength {
+		return fmt.Errorf("maximum code length must be between 0 (unlimited) and %d bits", maxCodeLength)
+	}
+	if h.BlockSize <= 0 {
+		return fmt.Errorf("block size must be positive")
+	}
+	if (h.Coding == Adaptive || h.Coding == Shared) && h.MaxCodeLength != 0 {
+		return fmt.Errorf("%v codes cannot be limited in length", h.Coding)
+	}
+
+	if _, err := w.WriteString(magic); err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte{formatVersion, flags, byte(h.MaxCodeLength)}); err != nil {
+		return err
+	}
+
+	if err := writeUvarint(w, uint64(h.BlockSize)); err != nil {
+		return err
+	}
+
+	if h.Coding == Shared {
+		return binary.Write(w, binary.BigEndian, h.Codebook)
+	}
+
+	return nil
+}
+
+// read reads the header of a stream of any supported format version and
+// returns the version
+func (h *Header) read(r *bufio.Reader) (byte, error) {
+	buf := [len(magic) + 3]byte{}
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	if string(buf[:len(magic)]) != magic {
+		return 0, ErrHeader
+	}
+
+	version := buf[len(magic)]
+	if version < 1 || version > formatVersion {
+		return 0, fmt.Errorf("%w %d", ErrVersion, version)
+	}
+
+	if err := h.setFlags(buf[len(magic)+1]); err != nil {
+		return 0, err
+	}
+
+	h.MaxCodeLength = int(buf[len(magic)+2])
+	if h.MaxCodeLength > maxCodeLength {
+		return 0, fmt.Errorf("maximum code length of %d bits exceeds %d bits", h.MaxCodeLength, maxCodeLength)
+	}
+
+	value, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if version == 1 {
+		if h.Indexed {
+			return 0, fmt.Errorf("reserved flags %#x are set", indexFlag)
+		}
+		if h.Coding != Static {
+			return 0, fmt.Errorf("unsupported coding %v", h.Coding)
+		}
+		h.Size = value
+		return version, nil
+	}
+
+	if value == 0 || value > math.MaxInt32 {
+		return 0, fmt.Errorf("block size of %d bytes is not supported", value)
+	}
+	h.BlockSize = int(value)
+
+	if h.Coding == Shared {
+		if err := binary.Read(r, binary.BigEndian, &h.Codebook); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+
+	return version, nil
+}
@@ -0,0 +1,168 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	headers := []Header{
+		{BlockSize: DefaultBlockSize},
+		{Alphabet: Bytes, Checksum: NoChecksum, MaxCodeLength: 15, BlockSize: 1},
+		{Alphabet: Runes, Checksum: CRC32, MaxCodeLength: maxCodeLength, BlockSize: 300},
+		{Coding: Shared, Codebook: 0xDEADBEEF, BlockSize: 300},
+	}
+
+	for _, expected := range headers {
+		buf := bytes.Buffer{}
+		w := bufio.NewWriter(&buf)
+		if err := expected.write(w); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+
+		if !bytes.HasPrefix(buf.Bytes(), []byte(magic)) {
+			t.Errorf("Expected header to begin with the magic bytes but received %q", buf.Bytes())
+		}
+
+		actual := Header{}
+		version, err := actual.read(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != formatVersion {
+			t.Errorf("Expected version %d but received %d", formatVersion, version)
+		}
+
+		if actual != expected {
+			t.Errorf("Expected header %+v but received %+v", expected, actual)
+		}
+	}
+}
+
+func compress(t *testing.T, original []byte, header Header) []byte {
+	t.Helper()
+
+	compressed := bytes.Buffer{}
+	writer := NewWriter(&compressed)
+	writer.Header = header
+	if _, err := writer.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return compressed.Bytes()
+}
+
+func decompress(compressed []byte) ([]byte, error) {
+	reader, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestInvalidStreams(t *testing.T) {
+	original := []byte("the quick brown fox jumps over the lazy dog")
+	compressed := compress(t, original, Header{})
+
+	if _, err := decompress([]byte("the quick brown fox")); !errors.Is(err, ErrHeader) {
+		t.Errorf("Expected %v for a file without magic bytes but received %v", ErrHeader, err)
+	}
+
+	future := append([]byte{}, compressed...)
+	future[len(magic)] = formatVersion + 1
+	if _, err := decompress(future); !errors.Is(err, ErrVersion) {
+		t.Errorf("Expected %v for a newer version but received %v", ErrVersion, err)
+	}
+
+	corrupted := append([]byte{}, compressed...)
+	corrupted[len(corrupted)-1] ^= 0x01
+	if _, err := decompress(corrupted); err != ErrChecksum {
+		t.Errorf("Expected %v for a corrupted checksum but received %v", ErrChecksum, err)
+	}
+
+	unchecked := compress(t, original, Header{Checksum: NoChecksum})
+	if len(unchecked) != len(compressed)-4 {
+		t.Errorf("Expected omitting the checksum to save 4 bytes, but received %d bytes instead of %d", len(unchecked), len(compressed))
+	}
+	decompressed, err := decompress(unchecked)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(original, decompressed) {
+		t.Errorf("Expected %q but received %q", original, decompressed)
+	}
+}
+
+func TestInvalidSymbolCount(t *testing.T) {
+	// withCount replaces the symbol count of the single block of a stream,
+	// which precedes the empty data length, the end of the blocks, the size
+	// and the checksum
+	withCount := func(original []byte, count uint64) []byte {
+		compressed := compress(t, original, Header{})
+		tail := compressed[len(compressed)-7:]
+
+		buf := make([]byte, binary.MaxVarintLen64)
+		crafted := append([]byte{}, compressed[:len(compressed)-8]...)
+		crafted = append(crafted, buf[:binary.PutUvarint(buf, count)]...)
+		return append(crafted, tail...)
+	}
+
+	// A single symbol has an empty code, so nothing but the count bounds how
+	// many are decoded
+	if _, err := decompress(withCount([]byte("a"), 1<<40)); !errors.Is(err, ErrSize) {
+		t.Errorf("Expected %v for more symbols than bytes but received %v", ErrSize, err)
+	}
+
+	// Six symbols fit in six bytes, but not when each takes two
+	if _, err := decompress(withCount([]byte("ééé"), 6)); !errors.Is(err, ErrSize) {
+		t.Errorf("Expected %v for symbols that overrun the block but received %v", ErrSize, err)
+	}
+}
+
+func TestVersion1Stream(t *testing.T) {
+	// v1-test.huf was written from legacy-test.txt before streams were split
+	// into blocks
+	original, err := os.ReadFile("legacy-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, err := os.ReadFile("v1-test.huf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Header{Size: uint64(len(original))}
+	if reader.Header != expected {
+		t.Errorf("Expected header %+v but received %+v", expected, reader.Header)
+	}
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(original, decompressed) {
+		t.Errorf("Expected %q but received %q", original, decompressed)
+	}
+
+	corrupted := append([]byte{}, compressed...)
+	corrupted[len(corrupted)-1] ^= 0x01
+	if _, err := decompress(corrupted); err != ErrChecksum {
+		t.Errorf("Expected %v but received %v", ErrChecksum, err)
+	}
+}
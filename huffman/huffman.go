@@ -0,0 +1,270 @@
+// Package huffman implements reading and writing of Huffman-coded data in the
+// style of compress/gzip: a Writer compresses whatever is written to it and a
+// Reader decompresses it again.
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+type FrequencyNode struct {
+	char rune
+	freq int
+	// first is the smallest symbol below an internal node, which places it
+	// among the nodes of the same weight
+	first rune
+	left  *FrequencyNode
+	right *FrequencyNode
+}
+
+func (fn *FrequencyNode) IsLeaf() bool {
+	return fn.left == nil && fn.right == nil
+}
+
+// smallest returns the smallest symbol at or below the node
+func (fn *FrequencyNode) smallest() rune {
+	if fn.IsLeaf() {
+		return fn.char
+	}
+	return fn.first
+}
+
+// less orders nodes by weight and then by their smallest symbol. The nodes
+// being merged into a tree never share a symbol, so this is a total order:
+// the tree depends only on the frequencies, never on the order the nodes
+// were queued in, and neither does the compressed output.
+func (fn *FrequencyNode) less(other *FrequencyNode) bool {
+	if fn.freq != other.freq {
+		return fn.freq < other.freq
+	}
+	return fn.smallest() < other.smallest()
+}
+
+func NewHuffmanTree(root *FrequencyNode) *HuffmanTree {
+	return &HuffmanTree{
+		root: root,
+	}
+}
+
+type HuffmanTree struct {
+	root *FrequencyNode
+}
+
+// Log writes a graphviz (dot) representation of the tree to w
+func (hf *HuffmanTree) Log(w io.Writer) error {
+	// Nodes are identified by their breadth-first position since neither
+	// weights nor chars are unique (canonical trees carry no weights at all)
+	queue := []*FrequencyNode{}
+	if hf.root != nil {
+		queue = append(queue, hf.root)
+	}
+
+	definitions := ""
+	connections := ""
+	for id := 0; id < len(queue); id++ {
+		node := queue[id]
+
+		if node.IsLeaf() {
+			definitions += fmt.Sprintf(` node_%d[label="char: %q\nrune: %d\nfreq: %d"];`, id, node.char, node.char, node.freq)
+		} else {
+			definitions += fmt.Sprintf(` node_%d[label="weight %d"];`, id, node.freq)
+			if node.left != nil {
+				queue = append(queue, node.left)
+				connections += fmt.Sprintf(` node_%d -- node_%d[label="%d"];`, id, len(queue)-1, 0)
+			}
+
+			if node.right != nil {
+				queue = append(queue, node.right)
+				connections += fmt.Sprintf(` node_%d -- node_%d[label="%d"];`, id, len(queue)-1, 1)
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `graph {
+		%s
+		%s
+	}`, definitions, connections)
+
+	return err
+}
+
+func (hf *HuffmanTree) ToLookupTable() map[rune]string {
+	table := make(map[rune]string)
+
+	var traverse func(n *FrequencyNode, code string)
+	traverse = func(n *FrequencyNode, code string) {
+		if n == nil {
+			return
+		}
+
+		// Pre-order traversal
+		// Ensure no non-character frequency nodes are written
+		// to the lookup table
+		if n.IsLeaf() {
+			table[n.char] = code
+		}
+		traverse(n.left, code+"0")
+		traverse(n.right, code+"1")
+	}
+
+	traverse(hf.root, "")
+
+	return table
+}
+
+// Code is a symbol's code as an integer: the Length least significant bits of
+// Bits, most significant bit first. Codes are at most maxCodeLength (64) bits,
+// so they always fit.
+type Code struct {
+	Bits   uint64
+	Length uint
+}
+
+// ToCodeTable returns the same codes as ToLookupTable in the form written by
+// BitWriter.WriteBits, so encoding writes each code at once rather than a bit
+// at a time
+func (hf *HuffmanTree) ToCodeTable() map[rune]Code {
+	table := make(map[rune]Code)
+
+	var traverse func(n *FrequencyNode, code Code)
+	traverse = func(n *FrequencyNode, code Code) {
+		if n == nil {
+			return
+		}
+
+		if n.IsLeaf() {
+			table[n.char] = code
+		}
+		traverse(n.left, Code{Bits: code.Bits << 1, Length: code.Length + 1})
+		traverse(n.right, Code{Bits: code.Bits<<1 | 1, Length: code.Length + 1})
+	}
+
+	traverse(hf.root, Code{})
+
+	return table
+}
+
+// WriteHeader writes the code lengths of the tree, which is all a reader needs
+// to rebuild canonical codes. The header is:
+//   - the number of symbols as a uvarint
+//   - for each symbol in canonical order (by code length, then by symbol), the
+//     increase of its code length over the previous symbol's in unary (that
+//     many one bits followed by a zero bit) and then the symbol itself in the
+//     given alphabet
+//   - zero bits up to the next byte boundary
+//
+// The tree's own codes should be canonical (see Canonical) for the header to
+// describe them.
+func (hf *HuffmanTree) WriteHeader(w *BitWriter, alphabet Alphabet) error {
+	if err := writeCodeLengths(w, hf.CodeLengths(), alphabet); err != nil {
+		return err
+	}
+	return w.Flush(Zero)
+}
+
+// writeCodeLengths writes the given code lengths in the layout described by
+// WriteHeader, without the padding
+func writeCodeLengths(w *BitWriter, lengths map[rune]int, alphabet Alphabet) error {
+	return writeLengths(w, lengths, func(s rune) error {
+		return alphabet.writeSymbol(w, s)
+	})
+}
+
+// writeLengths writes the given code lengths in the layout described by
+// WriteHeader, writing each symbol with writeSymbol
+func writeLengths(w *BitWriter, lengths map[rune]int, writeSymbol func(rune) error) error {
+	symbols := sortCanonical(lengths)
+
+	if err := writeUvarint(w, uint64(len(symbols))); err != nil {
+		return err
+	}
+
+	previous := 0
+	for _, s := range symbols {
+		for ; previous < lengths[s]; previous++ {
+			if err := w.WriteBit(One); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteBit(Zero); err != nil {
+			return err
+		}
+		if err := writeSymbol(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadHeader rebuilds the canonical tree written by WriteHeader with the same
+// alphabet
+func (hf *HuffmanTree) ReadHeader(r *BitReader, alphabet Alphabet) error {
+	lengths, err := readCodeLengths(r, alphabet)
+	if err != nil {
+		return err
+	}
+
+	// Discard the padding so the coded data begins on a byte boundary
+	if err := r.Flush(); err != nil {
+		return err
+	}
+
+	tree, err := NewCanonicalHuffmanTree(lengths)
+	if err != nil {
+		return err
+	}
+
+	hf.root = tree.root
+
+	return nil
+}
+
+// readCodeLengths reads the code lengths written by writeCodeLengths, leaving
+// the padding unread
+func readCodeLengths(r *BitReader, alphabet Alphabet) (map[rune]int, error) {
+	return readLengths(r, func() (rune, error) {
+		return alphabet.readSymbol(r)
+	})
+}
+
+// readLengths reads the code lengths written by writeLengths, reading each
+// symbol with readSymbol
+func readLengths(r *BitReader, readSymbol func() (rune, error)) (map[rune]int, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	lengths := make(map[rune]int)
+
+	length := 0
+	for i := uint64(0); i < count; i++ {
+		for {
+			bit, err := r.ReadBit()
+			if err != nil {
+				return nil, err
+			}
+			if bit == Zero {
+				break
+			}
+			length += 1
+			if length > maxCodeLength {
+				return nil, fmt.Errorf("code length exceeds %d bits", maxCodeLength)
+			}
+		}
+
+		s, err := readSymbol()
+		if err != nil {
+			return nil, err
+		}
+		if _, isDuplicate := lengths[s]; isDuplicate {
+			return nil, fmt.Errorf("symbol %q appears twice", s)
+		}
+		lengths[s] = length
+	}
+
+	return lengths, nil
+}
@@ -0,0 +1,701 @@
+package huffman
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBinaryTree(t *testing.T) {
+	// See https://opendsa-server.cs.vt.edu/ODSA/Books/CS3/html/Huffman.html#freqexamp
+	// for test tree walkthrough and structure
+	nodes := []*FrequencyNode{
+		{
+			char: 67, // C
+			freq: 32,
+		},
+		{
+			char: 68, // D
+			freq: 42,
+		},
+		{
+			char: 69, // E
+			freq: 120,
+		},
+		{
+			char: 75, // K
+			freq: 7,
+		},
+		{
+			char: 76, // L
+			freq: 42,
+		},
+		{
+			char: 77, // M
+			freq: 24,
+		},
+		{
+			char: 85, // U
+			freq: 37,
+		},
+		{
+			char: 90, // Z
+			freq: 2,
+		},
+	}
+
+	pq := NewPriorityQueue(nodes)
+
+	tree := NewHuffmanTree(pq.ToBinaryTree())
+
+	inOrderNodes := make([]*FrequencyNode, 0)
+
+	// Perform in-order depth-first traversal to capture a representation
+	// of the known Huffman tree to verify the tree's structure is correct.
+	// The true litmus test of success will be the prefix-free table; however,
+	// given the breakdown of the steps (i.e. generate the binary tree _before
+	// generating the lookup table), it's helpful to verify the tree's correctness
+	// independent of the Huffman codes.
+	var traverse func(n *FrequencyNode)
+	traverse = func(n *FrequencyNode) {
+		if n == nil {
+			return
+		}
+
+		traverse(n.left)
+		inOrderNodes = append(inOrderNodes, n)
+		traverse(n.right)
+	}
+
+	traverse(tree.root)
+
+	first := *inOrderNodes[0]
+	second := *inOrderNodes[1]
+	last := *inOrderNodes[len(inOrderNodes)-1]
+
+	if first.char != 69 && first.freq != 120 && first.IsLeaf() {
+		t.Errorf("Expected leaf node with char 69 ('E') with frequency 120, but received char %q with frequency %d", first.char, first.freq)
+	}
+
+	if second.char != 0 && second.freq != 306 && !second.IsLeaf() {
+		t.Errorf("Expected internal node with char 0 with frequency 306, but received char %q with frequency %d", second.char, second.freq)
+	}
+
+	if last.char != 77 && last.freq != 24 && last.IsLeaf() {
+		t.Errorf("Expected leaf node char 77 ('M') with frequency 24, but received char %q with frequency %d", last.char, last.freq)
+	}
+}
+
+func TestLookupTable(t *testing.T) {
+	// See https://opendsa-server.cs.vt.edu/ODSA/Books/CS3/html/Huffman.html#freqexamp
+	// for test tree walkthrough and structure
+	nodes := []*FrequencyNode{
+		{
+			char: 67, // C
+			freq: 32,
+		},
+		{
+			char: 68, // D
+			freq: 42,
+		},
+		{
+			char: 69, // E
+			freq: 120,
+		},
+		{
+			char: 75, // K
+			freq: 7,
+		},
+		{
+			char: 76, // L
+			freq: 42,
+		},
+		{
+			char: 77, // M
+			freq: 24,
+		},
+		{
+			char: 85, // U
+			freq: 37,
+		},
+		{
+			char: 90, // Z
+			freq: 2,
+		},
+	}
+
+	expectedCodes := []struct {
+		char rune
+		code string
+	}{
+		{
+			char: 67, // C
+			code: "1110",
+		},
+		{
+			char: 68, // D
+			code: "101",
+		},
+		{
+			char: 69, // E
+			code: "0",
+		},
+		{
+			char: 75, // K
+			code: "111101",
+		},
+		{
+			char: 76, // L
+			code: "110",
+		},
+		{
+			char: 77, // M
+			code: "11111",
+		},
+		{
+			char: 85, // U
+			code: "100",
+		},
+		{
+			char: 90, // Z
+			code: "111100",
+		},
+	}
+
+	pq := NewPriorityQueue(nodes)
+
+	tree := NewHuffmanTree(pq.ToBinaryTree())
+
+	table := tree.ToLookupTable()
+
+	for _, expected := range expectedCodes {
+		actualCode := table[expected.char]
+
+		if expected.code != actualCode {
+			t.Errorf("Expected %d char to have code %s, but received code %s", expected.char, expected.code, actualCode)
+		}
+	}
+
+}
+
+func TestHeader(t *testing.T) {
+	// See https://opendsa-server.cs.vt.edu/ODSA/Books/CS3/html/Huffman.html#freqexamp
+	// for test tree walkthrough and structure
+	nodes := []*FrequencyNode{
+		{
+			char: 67, // C
+			freq: 32,
+		},
+		{
+			char: 68, // D
+			freq: 42,
+		},
+		{
+			char: 69, // E
+			freq: 120,
+		},
+		{
+			char: 75, // K
+			freq: 7,
+		},
+		{
+			char: 76, // L
+			freq: 42,
+		},
+		{
+			char: 77, // M
+			freq: 24,
+		},
+		{
+			char: 85, // U
+			freq: 37,
+		},
+		{
+			char: 90, // Z
+			freq: 2,
+		},
+	}
+
+	pq := NewPriorityQueue(nodes)
+
+	inputTree := NewHuffmanTree(pq.ToBinaryTree()).Canonical()
+
+	header := bytes.Buffer{}
+
+	bitWriter := NewBitWriter(&header)
+
+	if err := inputTree.WriteHeader(bitWriter, Runes); err != nil {
+		t.Error(err)
+	}
+
+	// The symbols in canonical order with their code lengths are
+	// E:1 D:3 L:3 U:3 C:4 M:5 K:6 Z:6, so the header looks like this:
+	// "8 10E 110D 0L 0U 10C 10M 10K 0Z"
+	// This includes
+	// * 1 uvarint symbol count (8), which accounts for 1 byte
+	// * 8 1-byte runes (E, D, L, U, C, M, K, Z) which accounts for 8 bytes
+	// * 14 unary code length bits, which are padded with 2 zero bits to
+	// account for 2 bytes
+	// This makes a total of 11 bytes
+
+	if header.Len() != 11 {
+		t.Errorf("Expected header to be 11 bytes long, but received %d bytes", header.Len())
+	}
+
+	bitReader := NewBitReader(&header)
+
+	outputTree := &HuffmanTree{}
+
+	if err := outputTree.ReadHeader(bitReader, Runes); err != nil {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual(inputTree.ToLookupTable(), outputTree.ToLookupTable()) {
+		t.Error("expected input lookup table to equal output lookup table", inputTree.ToLookupTable(), outputTree.ToLookupTable())
+	}
+
+	if _, err := bitReader.ReadBit(); err != io.EOF {
+		t.Errorf("expected EOF error but received %v instead", err)
+	}
+}
+
+func TestCanonicalCodes(t *testing.T) {
+	lengths := map[rune]int{
+		'C': 4,
+		'D': 3,
+		'E': 1,
+		'K': 6,
+		'L': 3,
+		'M': 5,
+		'U': 3,
+		'Z': 6,
+	}
+
+	expected := map[rune]string{
+		'E': "0",
+		'D': "100",
+		'L': "101",
+		'U': "110",
+		'C': "1110",
+		'M': "11110",
+		'K': "111110",
+		'Z': "111111",
+	}
+
+	tree, err := NewCanonicalHuffmanTree(lengths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, tree.ToLookupTable()) {
+		t.Errorf("Expected canonical codes %v but received %v", expected, tree.ToLookupTable())
+	}
+
+	// Lengths are preserved, so re-canonicalizing changes nothing
+	if !reflect.DeepEqual(lengths, tree.Canonical().CodeLengths()) {
+		t.Errorf("Expected code lengths %v but received %v", lengths, tree.Canonical().CodeLengths())
+	}
+
+	// Three 1-bit codes cannot exist
+	if _, err := NewCanonicalHuffmanTree(map[rune]int{'a': 1, 'b': 1, 'c': 1}); err == nil {
+		t.Error("Expected over-subscribed code lengths to be rejected")
+	}
+}
+
+// roundTrip compresses original with the given header settings and returns
+// the decompressed result
+func roundTrip(t *testing.T, original []byte, header Header) []byte {
+	t.Helper()
+
+	compressed := bytes.Buffer{}
+	writer := NewWriter(&compressed)
+	writer.Header = header
+
+	if _, err := writer.Write(original); err != nil {
+		t.Errorf("failed to compress: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("failed to compress: %v", err)
+	}
+
+	reader, err := NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Errorf("failed to decompress: %v", err)
+	}
+
+	// The size is only known once the trailer has been read
+	if reader.Header != writer.Header {
+		t.Errorf("expected header %+v but received %+v", writer.Header, reader.Header)
+	}
+
+	return decompressed
+}
+
+func TestCompression(t *testing.T) {
+	inputs := []string{"frequency-test.txt", "les-mis-test.txt"}
+
+	for _, input := range inputs {
+		for _, coding := range []Coding{Static, Adaptive, Context} {
+			for _, alphabet := range []Alphabet{Runes, Bytes} {
+				t.Run(input+"/"+coding.String()+"/"+alphabet.String(), func(t *testing.T) {
+					original, err := os.ReadFile(input)
+					if os.IsNotExist(err) {
+						t.Skipf("%s is not available", input)
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					decompressed := roundTrip(t, original, Header{Alphabet: alphabet, Coding: coding})
+
+					if md5.Sum(original) != md5.Sum(decompressed) {
+						t.Error("Expected decompressed to be identical to original file")
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestCompressionBinary(t *testing.T) {
+	// Every byte value, including NUL and bytes that are never valid UTF-8,
+	// weighted so that the tree has a variety of code lengths
+	original := make([]byte, 0)
+	for i := 0; i < 256; i++ {
+		for j := 0; j <= i%7; j++ {
+			original = append(original, byte(i))
+		}
+	}
+
+	for _, coding := range []Coding{Static, Adaptive, Context} {
+		decompressed := roundTrip(t, original, Header{Alphabet: Bytes, Coding: coding})
+
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("Expected decompressed to be identical to original bytes with %v codes", coding)
+		}
+	}
+}
+
+func TestCompressionInvalidUTF8(t *testing.T) {
+	// Latin-1 encoded text, a genuine U+FFFD, a truncated 3-byte sequence and
+	// an encoded surrogate half, none of which may be altered by the round trip
+	original := []byte("caf\xe9 cr\xe8me br\xfbl\xe9e � \xe2\x82 \xed\xa0\x80 ⁂ fin\n")
+
+	for _, coding := range []Coding{Static, Adaptive, Context} {
+		decompressed := roundTrip(t, original, Header{Alphabet: Runes, Coding: coding})
+
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("Expected %q but received %q with %v codes", original, decompressed, coding)
+		}
+	}
+}
+
+func TestDegenerateInputs(t *testing.T) {
+	inputs := []string{"", "a", "aaaaaaaaaaaaaaaaaaaaaa", "\x00", "\x00\x00\x00", "a\x00b\x00\x00c", "\xff\xff"}
+
+	for _, input := range inputs {
+		for _, coding := range []Coding{Static, Adaptive, Context} {
+			for _, alphabet := range []Alphabet{Runes, Bytes} {
+				original := []byte(input)
+				decompressed := roundTrip(t, original, Header{Alphabet: alphabet, Coding: coding})
+
+				if !bytes.Equal(original, decompressed) {
+					t.Errorf("Expected %q but received %q with %v codes of %v", original, decompressed, coding, alphabet)
+				}
+			}
+		}
+	}
+}
+
+func TestDegenerateTrees(t *testing.T) {
+	if root := NewPriorityQueue(nil).ToBinaryTree(); root != nil {
+		t.Errorf("Expected no tree without symbols but received %+v", root)
+	}
+
+	empty, err := NewCanonicalHuffmanTree(map[rune]int{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.ToLookupTable()) != 0 || len(empty.CodeLengths()) != 0 {
+		t.Error("Expected an empty tree to have no codes")
+	}
+	if err := empty.Log(io.Discard); err != nil {
+		t.Error(err)
+	}
+
+	// NUL is a symbol like any other, whether alone or among others
+	for _, lengths := range []map[rune]int{{0: 0}, {0: 1, 'a': 2, 'b': 2}, {}} {
+		tree, err := NewCanonicalHuffmanTree(lengths)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header := bytes.Buffer{}
+		if err := tree.WriteHeader(NewBitWriter(&header), Runes); err != nil {
+			t.Fatal(err)
+		}
+
+		read := &HuffmanTree{}
+		if err := read.ReadHeader(NewBitReader(&header), Runes); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(read.CodeLengths(), lengths) {
+			t.Errorf("Expected code lengths %v but received %v", lengths, read.CodeLengths())
+		}
+	}
+
+	single := NewHuffmanTree(NewPriorityQueue([]*FrequencyNode{{char: 0, freq: 3}}).ToBinaryTree())
+	if codes := single.ToLookupTable(); !reflect.DeepEqual(codes, map[rune]string{0: ""}) {
+		t.Errorf("Expected an empty code for a lone NUL but received %q", codes)
+	}
+}
+
+func TestHeaderEscapes(t *testing.T) {
+	ft := NewFrequencyTable(Runes)
+
+	if err := ft.Populate(bytes.NewReader([]byte("a\xffb\xe9�a"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if ft.Get(escapeBase+0xff) != 1 || ft.Get(escapeBase+0xe9) != 1 || ft.Get(utf8.RuneError) != 1 {
+		t.Error("Expected invalid bytes to be counted as escapes apart from U+FFFD")
+	}
+
+	inputTree := NewHuffmanTree(NewPriorityQueue(ft.ToList()).ToBinaryTree()).Canonical()
+
+	header := bytes.Buffer{}
+
+	if err := inputTree.WriteHeader(NewBitWriter(&header), Runes); err != nil {
+		t.Fatal(err)
+	}
+
+	outputTree := &HuffmanTree{}
+
+	if err := outputTree.ReadHeader(NewBitReader(&header), Runes); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(inputTree.ToLookupTable(), outputTree.ToLookupTable()) {
+		t.Error("expected input lookup table to equal output lookup table", inputTree.ToLookupTable(), outputTree.ToLookupTable())
+	}
+}
+
+func TestEndOfStream(t *testing.T) {
+	// With a code for every byte value, the padding of the final byte always
+	// spells out some code; the symbol count must stop the reader before it.
+	// A lone NUL has an empty code, so all of its final byte is padding.
+	original := []byte{}
+	for length := 0; length < 16; length++ {
+		original = append(original, byte(length))
+
+		for _, coding := range []Coding{Static, Adaptive, Context} {
+			decompressed := roundTrip(t, original, Header{Alphabet: Bytes, Coding: coding})
+
+			if !bytes.Equal(original, decompressed) {
+				t.Errorf("Expected %v but received %v with %v codes", original, decompressed, coding)
+			}
+		}
+	}
+
+	compressed := bytes.Buffer{}
+	writer := NewWriter(&compressed)
+	writer.Write([]byte("abracadabra"))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	trailing := append(append([]byte{}, compressed.Bytes()...), 0)
+	reader, err := NewReader(bytes.NewReader(trailing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(reader); err != ErrTrailingData {
+		t.Errorf("Expected %v for trailing data but received %v", ErrTrailingData, err)
+	}
+
+	truncated := compressed.Bytes()[:compressed.Len()-1]
+	reader, err = NewReader(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(reader); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected %v for truncated data but received %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	// Text followed by base64-like data, whose statistics differ
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 100)
+	blob := make([]byte, 0, 4096)
+	for i := 0; len(blob) < cap(blob); i++ {
+		blob = append(blob, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"[i*37%64])
+	}
+	original := append(append([]byte{}, text...), blob...)
+
+	sizes := make(map[int]int)
+	for _, blockSize := range []int{7, 64, len(text), 0} {
+		compressed := bytes.Buffer{}
+		writer := NewWriter(&compressed)
+		writer.BlockSize = blockSize
+		writer.Concurrency = 1
+
+		// Writing in chunks that do not line up with the blocks
+		for p := original; len(p) > 0; {
+			n := 1000
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := writer.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+
+		// With a single block coded at a time, a block is written as soon as
+		// the next one is started
+		if blockSize != 0 && 2*blockSize < len(original) && compressed.Len() == 0 {
+			t.Errorf("Expected blocks of %d bytes to be written before Close", blockSize)
+		}
+
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sizes[blockSize] = compressed.Len()
+
+		decompressed, err := decompress(compressed.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("Expected blocks of %d bytes to round-trip", blockSize)
+		}
+	}
+
+	if sizes[len(text)] >= sizes[0] {
+		t.Errorf("Expected a tree per section (%d bytes) to beat a single tree (%d bytes)", sizes[len(text)], sizes[0])
+	}
+}
+
+func TestBlockBoundaries(t *testing.T) {
+	original := []byte("ünïcödé ünïcödé ünïcödé")
+
+	// Block sizes that split characters, including blocks of a single
+	// character or escaped byte
+	for _, blockSize := range []int{1, 2, 3, 5, 6, 9, 11} {
+		decompressed := roundTrip(t, original, Header{BlockSize: blockSize})
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("Expected blocks of %d bytes to round-trip but received %q", blockSize, decompressed)
+		}
+	}
+
+	if n := Runes.boundary([]byte("aü")[:2]); n != 1 {
+		t.Errorf("Expected a split character to be left for the next block but received %d", n)
+	}
+	if n := Runes.boundary([]byte("aü")); n != 3 {
+		t.Errorf("Expected a complete character to stay in the block but received %d", n)
+	}
+	if n := Bytes.boundary([]byte("aü")[:2]); n != 2 {
+		t.Errorf("Expected bytes to split anywhere but received %d", n)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	original := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 1000)
+	for i := range original {
+		// Vary the statistics so that blocks get different trees
+		if i%(i/1000+2) == 0 {
+			original[i] = 'a' + byte(i/1000)
+		}
+	}
+
+	for _, concurrency := range []int{1, 2, 7, 0} {
+		compressed := bytes.Buffer{}
+		writer := NewWriter(&compressed)
+		writer.BlockSize = 1000
+		writer.Concurrency = concurrency
+
+		for p := original; len(p) > 0; {
+			n := 333
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := writer.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		decompressed, err := decompress(compressed.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("Expected blocks coded %d at a time to be written in order", concurrency)
+		}
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	// Many symbols tie in weight, so any dependence on the order of map
+	// iteration while building trees would show in the output
+	original := bytes.Repeat([]byte("abcdefghij\x00abcdeghij aabbccdd, ééé! "), 200)
+
+	// The output is part of the format, so it must not change between runs,
+	// machines or versions of Go
+	expected := map[Coding]string{
+		Static:   "c615a69bb4bf8feebc77a0b2dee1e68d",
+		Adaptive: "b29dee916aeb505e1fe667fdb7238173",
+		Context:  "0ff5f39537e58ef759a8ece0db0558d9",
+	}
+
+	for _, coding := range []Coding{Static, Adaptive, Context} {
+		header := Header{Coding: coding, BlockSize: 1000, Indexed: true}
+
+		first := compress(t, original, header)
+		if sum := fmt.Sprintf("%x", md5.Sum(first)); sum != expected[coding] {
+			t.Errorf("Expected %v output with an MD5 of %s but received %s", coding, expected[coding], sum)
+		}
+
+		for i := 0; i < 20; i++ {
+			if compressed := compress(t, original, header); !bytes.Equal(first, compressed) {
+				t.Fatalf("Expected every compression with %v codes to give the same output", coding)
+			}
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	corpus := benchmarkCorpus(b)
+
+	for _, concurrency := range []int{1, 0} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			b.SetBytes(int64(len(corpus)))
+
+			for i := 0; i < b.N; i++ {
+				writer := NewWriter(io.Discard)
+				writer.BlockSize = 16 << 10
+				writer.Concurrency = concurrency
+				if _, err := writer.Write(corpus); err != nil {
+					b.Fatal(err)
+				}
+				if err := writer.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"sort"
+)
+
+var (
+	ErrIndex   = errors.New("huffman: invalid index")
+	ErrNoIndex = errors.New("huffman: stream has no index")
+)
+
+// indexFooterLength is the size of the big-endian length of the index that
+// ends an indexed stream, which lets the index be found from the end
+const indexFooterLength = 8
+
+// DefaultCheckpointInterval is the checkpoint interval used when a Writer's
+// CheckpointInterval is zero
+const DefaultCheckpointInterval = 64 << 10
+
+// indexEntry locates a block: offset is the position of its length in the
+// stream and start is the position of its first byte in the original data
+type indexEntry struct {
+	offset      uint64
+	start       uint64
+	checkpoints []checkpoint
+}
+
+// checkpoint is a point within a block at which decoding can begin: bits is the
+// position in the coded data, offset the position in the original data of the
+// block and symbols the number of symbols that precede it
+type checkpoint struct {
+	bits    uint64
+	offset  uint64
+	symbols uint64
+}
+
+// writeIndex writes the index of an indexed stream, which follows the trailer:
+//   - the number of blocks as a uvarint
+//   - for each block, the offset in the stream and the offset in the original
+//     data as uvarints, followed by the number of checkpoints and the bits,
+//     offset and symbols of each as uvarints
+//   - the offsets of the end of the blocks as uvarints
+//   - the length of the above in bytes as a big-endian uint64
+func writeIndex(w io.Writer, index []indexEntry) error {
+	buf := bytes.Buffer{}
+
+	if err := writeUvarint(&buf, uint64(len(index)-1)); err != nil {
+		return err
+	}
+	for i, entry := range index {
+		if err := writeUvarint(&buf, entry.offset); err != nil {
+			return err
+		}
+		if err := writeUvarint(&buf, entry.start); err != nil {
+			return err
+		}
+		if i == len(index)-1 {
+			break
+		}
+
+		if err := writeUvarint(&buf, uint64(len(entry.checkpoints))); err != nil {
+			return err
+		}
+		for _, c := range entry.checkpoints {
+			for _, v := range []uint64{c.bits, c.offset, c.symbols} {
+				if err := writeUvarint(&buf, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := binary.Write(&buf, binary.BigEndian, uint64(buf.Len())); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// readIndex parses p, which must hold exactly the index written by writeIndex
+// including its length, and returns its entries, the last of which locates the
+// end of the blocks
+func readIndex(p []byte) ([]indexEntry, error) {
+	if len(p) < indexFooterLength {
+		return nil, ErrIndex
+	}
+	length := binary.BigEndian.Uint64(p[len(p)-indexFooterLength:])
+	if length != uint64(len(p)-indexFooterLength) {
+		return nil, ErrIndex
+	}
+
+	r := bytes.NewReader(p[:length])
+
+	count, err := binary.ReadUvarint(r)
+	// Every entry takes at least two bytes
+	if err != nil || count >= length/2 {
+		return nil, ErrIndex
+	}
+
+	index := make([]indexEntry, count+1)
+	for i := range index {
+		if index[i].offset, err = binary.ReadUvarint(r); err != nil {
+			return nil, ErrIndex
+		}
+		if index[i].start, err = binary.ReadUvarint(r); err != nil {
+			return nil, ErrIndex
+		}
+
+		// Blocks are never empty, so both offsets only ever increase
+		if i > 0 && (index[i].offset <= index[i-1].offset || index[i].start <= index[i-1].start) {
+			return nil, ErrIndex
+		}
+		if i == len(index)-1 {
+			break
+		}
+
+		checkpoints, err := binary.ReadUvarint(r)
+		if err != nil || checkpoints > uint64(r.Len())/3 {
+			return nil, ErrIndex
+		}
+
+		index[i].checkpoints = make([]checkpoint, checkpoints)
+		previous := checkpoint{}
+		for j := range index[i].checkpoints {
+			c := &index[i].checkpoints[j]
+			for _, v := range []*uint64{&c.bits, &c.offset, &c.symbols} {
+				if *v, err = binary.ReadUvarint(r); err != nil {
+					return nil, ErrIndex
+				}
+			}
+
+			// Every symbol takes at least a byte and a checkpoint follows at
+			// least one symbol
+			if c.offset <= previous.offset || c.symbols <= previous.symbols || c.bits < previous.bits || c.symbols > c.offset {
+				return nil, ErrIndex
+			}
+			previous = *c
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrIndex
+	}
+
+	// Checkpoints lie within their block
+	for i, entry := range index[:len(index)-1] {
+		if n := len(entry.checkpoints); n > 0 && entry.checkpoints[n-1].offset >= index[i+1].start-entry.start {
+			return nil, ErrIndex
+		}
+	}
+
+	return index, nil
+}
+
+// countingWriter counts the bytes written through it, which gives the offsets
+// of the blocks of an indexed stream
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReader counts the bytes read through it, which lets a Reader check
+// the offsets recorded in the index
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// BlockReader decodes an indexed stream (see Header.Indexed) held in an
+// io.ReaderAt. The index locates every block and checkpoints within them, so
+// unlike a Reader it decodes blocks concurrently and can decode any range of
+// the original data without decoding what precedes it.
+type BlockReader struct {
+	Header
+	// Concurrency is the most blocks decoded at once, each on its own
+	// goroutine; the zero value uses runtime.GOMAXPROCS(0)
+	Concurrency int
+	r           io.ReaderAt
+	index       []indexEntry
+	codebook    *SharedCodebook
+	digest      uint32
+}
+
+// NewBlockReader reads the header, trailer and index of the size bytes of r.
+// ErrNoIndex is returned for a stream that was not written with an index, which
+// a Reader can still decode. The codebooks are those of NewReader.
+func NewBlockReader(r io.ReaderAt, size int64, codebooks ...*SharedCodebook) (*BlockReader, error) {
+	z := &BlockReader{
+		r: r,
+	}
+
+	// Legacy streams have no header, let alone an index
+	head := make([]byte, 1)
+	if _, err := r.ReadAt(head, 0); err == nil && isLegacy(head[0]) {
+		return nil, ErrNoIndex
+	}
+
+	version, err := z.Header.read(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+	if err != nil {
+		return nil, err
+	}
+	if version < 2 || !z.Indexed {
+		return nil, ErrNoIndex
+	}
+
+	if z.Coding == Shared {
+		if z.codebook, err = findCodebook(z.Header.Codebook, codebooks); err != nil {
+			return nil, err
+		}
+		if z.codebook.Alphabet != z.Alphabet {
+			return nil, ErrHeader
+		}
+	}
+
+	footer := make([]byte, indexFooterLength)
+	if _, err := r.ReadAt(footer, size-indexFooterLength); err != nil {
+		return nil, ErrIndex
+	}
+	length := binary.BigEndian.Uint64(footer)
+	if length > uint64(size-indexFooterLength) {
+		return nil, ErrIndex
+	}
+
+	indexOffset := size - indexFooterLength - int64(length)
+	p := make([]byte, length+indexFooterLength)
+	if _, err := r.ReadAt(p, indexOffset); err != nil {
+		return nil, ErrIndex
+	}
+	if z.index, err = readIndex(p); err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(z.index); i++ {
+		if z.index[i].start-z.index[i-1].start > uint64(z.BlockSize) {
+			return nil, ErrIndex
+		}
+	}
+
+	end := z.index[len(z.index)-1]
+	if end.offset >= uint64(indexOffset) {
+		return nil, ErrIndex
+	}
+	z.Size = end.start
+
+	if err := z.readTrailer(io.NewSectionReader(r, int64(end.offset), indexOffset-int64(end.offset))); err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+// readTrailer reads the end of the blocks and the trailer, which must take up
+// all of r
+func (z *BlockReader) readTrailer(r io.Reader) error {
+	input := bufio.NewReader(r)
+
+	if marker, err := binary.ReadUvarint(input); marker != 0 || err != nil {
+		return ErrIndex
+	}
+
+	if size, err := binary.ReadUvarint(input); size != z.Size || err != nil {
+		return ErrIndex
+	}
+
+	if z.Checksum == CRC32 {
+		if err := binary.Read(input, binary.BigEndian, &z.digest); err != nil {
+			return ErrIndex
+		}
+	}
+
+	if _, err := input.ReadByte(); err != io.EOF {
+		return ErrIndex
+	}
+
+	return nil
+}
+
+// decodedBlock is a block being decoded by its own goroutine. done is closed
+// once data holds the decoded block or err is set.
+type decodedBlock struct {
+	data []byte
+	err  error
+	done chan struct{}
+}
+
+// WriteTo decodes every block, up to Concurrency at a time, and writes them to
+// w in order. The checksum is verified once all blocks have been written.
+func (z *BlockReader) WriteTo(w io.Writer) (int64, error) {
+	concurrency := z.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	pending := make([]*decodedBlock, 0, concurrency)
+	free := make([][]byte, 0, concurrency)
+
+	var digest uint32
+	var n int64
+
+	for next := 0; next < len(z.index)-1 || len(pending) > 0; {
+		for ; next < len(z.index)-1 && len(pending) < concurrency; next++ {
+			b := &decodedBlock{
+				done: make(chan struct{}),
+			}
+			if len(free) > 0 {
+				b.data, free = free[len(free)-1], free[:len(free)-1]
+			}
+
+			entry, following := z.index[next], z.index[next+1]
+			section := io.NewSectionReader(z.r, int64(entry.offset), int64(following.offset-entry.offset))
+
+			go func(length uint64) {
+				b.data, b.err = decodeBlock(section, z.Header, z.codebook, b.data[:0])
+				if b.err == nil && uint64(len(b.data)) != length {
+					b.err = ErrIndex
+				}
+				close(b.done)
+			}(following.start - entry.start)
+
+			pending = append(pending, b)
+		}
+
+		b := pending[0]
+		<-b.done
+		pending = pending[:copy(pending, pending[1:])]
+
+		if b.err != nil {
+			return n, b.err
+		}
+
+		written, err := w.Write(b.data)
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+
+		digest = crc32.Update(digest, crc32.IEEETable, b.data)
+		free = append(free, b.data)
+	}
+
+	if z.Checksum == CRC32 && digest != z.digest {
+		return n, ErrChecksum
+	}
+
+	return n, nil
+}
+
+// decodeBlock decodes the single block that r begins with and appends it to p.
+// The block must have been written with the settings in h and, for Shared
+// codes, the codebook.
+func decodeBlock(r io.Reader, h Header, codebook *SharedCodebook, p []byte) ([]byte, error) {
+	z := &Reader{
+		Header:   h,
+		count:    countingReader{r: r},
+		version:  formatVersion,
+		codebook: codebook,
+		buf:      p,
+	}
+	z.input = bufio.NewReader(&z.count)
+
+	if err := z.nextBlock(); err != nil {
+		return p, err
+	}
+	if z.end {
+		return p, ErrIndex
+	}
+
+	for z.remaining > 0 {
+		if err := z.decodeSymbols(); err != nil {
+			return z.buf, err
+		}
+	}
+
+	if err := finishData(z.bits); err != nil {
+		return z.buf, err
+	}
+	if z.blockDecoded != z.blockLength {
+		return z.buf, ErrSize
+	}
+
+	return z.buf, nil
+}
+
+// ReadAt decodes len(p) bytes of the original data starting at off, satisfying
+// io.ReaderAt. Decoding begins at the closest checkpoint before off, so only the
+// data between the two is decoded needlessly. The checksum covers the whole of
+// the original data and so is not verified. Wrapping the BlockReader with
+// io.NewSectionReader gives a Reader that can Seek.
+func (z *BlockReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("huffman: negative offset")
+	}
+
+	n := 0
+	for n < len(p) && uint64(off)+uint64(n) < z.Size {
+		position := uint64(off) + uint64(n)
+
+		// The block containing position is the last one starting at or before it
+		i := sort.Search(len(z.index)-1, func(i int) bool {
+			return z.index[i+1].start > position
+		})
+
+		read, err := z.readBlockAt(p[n:], i, position-z.index[i].start)
+		n += read
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// readBlockAt decodes block i from the closest checkpoint before offset, which
+// is relative to the start of the block, and copies as much of the block from
+// offset as fits into p
+func (z *BlockReader) readBlockAt(p []byte, i int, offset uint64) (int, error) {
+	entry, following := z.index[i], z.index[i+1]
+	section := io.NewSectionReader(z.r, int64(entry.offset), int64(following.offset-entry.offset))
+
+	d := &Reader{
+		Header:   z.Header,
+		count:    countingReader{r: section},
+		version:  formatVersion,
+		codebook: z.codebook,
+	}
+	d.input = bufio.NewReader(&d.count)
+
+	if err := d.nextBlock(); err != nil {
+		return 0, err
+	}
+	if d.end || d.blockLength != following.start-entry.start {
+		return 0, ErrIndex
+	}
+
+	start := checkpoint{}
+	if j := sort.Search(len(entry.checkpoints), func(j int) bool {
+		return entry.checkpoints[j].offset > offset
+	}); j > 0 {
+		// Other codes depend on the preceding symbols of the block
+		if !z.Coding.resumable() {
+			return 0, ErrIndex
+		}
+		start = entry.checkpoints[j-1]
+
+		// The coded data runs for dataLength bytes from just after the
+		// block's header. A checkpoint may lie at its very end, as every
+		// checkpoint does when the block's single symbol has an empty code.
+		if start.bits > 8*d.dataLength || start.symbols >= d.remaining {
+			return 0, ErrIndex
+		}
+		skip := d.count.n - int64(d.input.Buffered()) + int64(start.bits/8)
+
+		d.bits = NewBitReader(io.NewSectionReader(section, skip, int64(d.dataLength-start.bits/8)))
+		if err := d.bits.Skip(uint(start.bits % 8)); err != nil {
+			return 0, err
+		}
+		d.remaining -= start.symbols
+		d.blockDecoded = start.offset
+	}
+
+	end := offset + uint64(len(p))
+	if end > d.blockLength {
+		end = d.blockLength
+	}
+
+	for start.offset+uint64(len(d.buf)) < end && d.remaining > 0 {
+		if err := d.decodeSymbols(); err != nil {
+			return 0, err
+		}
+	}
+
+	if start.offset+uint64(len(d.buf)) < end {
+		return 0, ErrIndex
+	}
+
+	return copy(p, d.buf[offset-start.offset:end-start.offset]), nil
+}
@@ -0,0 +1,233 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestIndexedStream(t *testing.T) {
+	original := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 200)
+	for i := range original {
+		if i%(i/500+2) == 0 {
+			original[i] = 'a' + byte(i/500)
+		}
+	}
+
+	for _, input := range [][]byte{original, original[:100], {}} {
+		compressed := compress(t, input, Header{BlockSize: 500, Indexed: true})
+
+		// A Reader decodes indexed streams like any other
+		decompressed, err := decompress(compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(input, decompressed) {
+			t.Errorf("Expected the Reader to decode %d bytes", len(input))
+		}
+
+		for _, concurrency := range []int{1, 3, 0} {
+			reader, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader.Concurrency = concurrency
+
+			if reader.Size != uint64(len(input)) {
+				t.Errorf("Expected a size of %d but received %d", len(input), reader.Size)
+			}
+
+			decompressed := bytes.Buffer{}
+			n, err := reader.WriteTo(&decompressed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(len(input)) || !bytes.Equal(input, decompressed.Bytes()) {
+				t.Errorf("Expected blocks decoded %d at a time to be written in order", concurrency)
+			}
+		}
+	}
+}
+
+func TestInvalidIndex(t *testing.T) {
+	original := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 50)
+
+	plain := compress(t, original, Header{BlockSize: 500})
+	if _, err := NewBlockReader(bytes.NewReader(plain), int64(len(plain))); err != ErrNoIndex {
+		t.Errorf("Expected %v for a stream without an index but received %v", ErrNoIndex, err)
+	}
+
+	legacy, err := os.ReadFile("legacy-test.huf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewBlockReader(bytes.NewReader(legacy), int64(len(legacy))); err != ErrNoIndex {
+		t.Errorf("Expected %v for a legacy stream but received %v", ErrNoIndex, err)
+	}
+
+	compressed := compress(t, original, Header{BlockSize: 500, Indexed: true})
+	footer := len(compressed) - indexFooterLength
+
+	// Moving the second block's offset (after the block count, the first
+	// block's offsets and its checkpoint count) makes it begin part way
+	// through the first block
+	moved := append([]byte{}, compressed...)
+	length := binary.BigEndian.Uint64(moved[footer:])
+	moved[footer-int(length)+4] -= 1
+
+	truncated := compressed[:len(compressed)-1]
+
+	lengthened := append([]byte{}, compressed...)
+	lengthened[len(lengthened)-1] += 1
+
+	for name, stream := range map[string][]byte{"moved": moved, "truncated": truncated, "lengthened": lengthened} {
+		reader, err := NewBlockReader(bytes.NewReader(stream), int64(len(stream)))
+		if err == nil {
+			_, err = reader.WriteTo(&bytes.Buffer{})
+		}
+		if err == nil {
+			t.Errorf("Expected an error for the %s index", name)
+		}
+
+		if _, err := decompress(stream); err == nil {
+			t.Errorf("Expected the Reader to reject the %s index", name)
+		}
+	}
+
+	corrupted := append([]byte{}, compressed...)
+	corrupted[footer-1] ^= 0x01
+	if _, err := decompress(corrupted); !errors.Is(err, ErrIndex) {
+		t.Errorf("Expected %v but received %v", ErrIndex, err)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	original := bytes.Repeat([]byte("ünïcödé text, then plain text; "), 300)
+
+	writer := bytes.Buffer{}
+	w := NewWriter(&writer)
+	w.BlockSize = 1000
+	w.Indexed = true
+	w.CheckpointInterval = 100
+	if _, err := w.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressed := writer.Bytes()
+
+	reader, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.index[0].checkpoints) != 9 {
+		t.Errorf("Expected 9 checkpoints in a block of 1000 bytes but received %d", len(reader.index[0].checkpoints))
+	}
+
+	// Ranges within a block, across checkpoints and blocks, part way through
+	// characters and up to the end
+	ranges := []struct{ offset, length int }{
+		{0, 10}, {1, 1}, {99, 3}, {950, 100}, {1001, 2500}, {len(original) - 5, 5}, {0, len(original)},
+	}
+	for offset := 0; offset < len(original); offset += 377 {
+		ranges = append(ranges, struct{ offset, length int }{offset, 61})
+	}
+
+	for _, r := range ranges {
+		p := make([]byte, r.length)
+		n, err := reader.ReadAt(p, int64(r.offset))
+		if err != nil {
+			t.Fatalf("failed to read %d bytes at %d: %v", r.length, r.offset, err)
+		}
+		if n != r.length || !bytes.Equal(p, original[r.offset:r.offset+r.length]) {
+			t.Errorf("Expected %q at %d but received %q", original[r.offset:r.offset+r.length], r.offset, p[:n])
+		}
+	}
+
+	p := make([]byte, 10)
+	if n, err := reader.ReadAt(p, int64(len(original)-4)); n != 4 || err != io.EOF {
+		t.Errorf("Expected 4 bytes and %v at the end but received %d and %v", io.EOF, n, err)
+	}
+
+	// A section reader adds Seek and Read on top of ReadAt
+	section := io.NewSectionReader(reader, 0, int64(reader.Size))
+	if _, err := section.Seek(2000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := io.ReadAll(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, original[2000:]) {
+		t.Error("Expected to read the rest of the data after seeking")
+	}
+}
+
+func TestReadAtSingleSymbol(t *testing.T) {
+	// The only symbol of the block has an empty code, so the coded data is
+	// empty and every checkpoint lies at its end
+	original := bytes.Repeat([]byte{'a'}, 200000)
+	compressed := compress(t, original, Header{Indexed: true})
+
+	reader, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.index[0].checkpoints) == 0 {
+		t.Fatal("Expected checkpoints in a block of 200000 bytes")
+	}
+
+	for _, offset := range []int64{0, 65535, 65536, 100000, 199995} {
+		p := make([]byte, 5)
+		if _, err := reader.ReadAt(p, offset); err != nil {
+			t.Fatalf("failed to read 5 bytes at %d: %v", offset, err)
+		}
+		if !bytes.Equal(p, original[offset:offset+5]) {
+			t.Errorf("Expected %q at %d but received %q", original[offset:offset+5], offset, p)
+		}
+	}
+}
+
+func TestReadAtWithinRunes(t *testing.T) {
+	// Characters of two, three and four bytes, with checkpoints close
+	// together, so that ranges begin and end part way through characters
+	// on either side of a checkpoint
+	original := bytes.Repeat([]byte("é日本語🙂x"), 100)
+
+	writer := bytes.Buffer{}
+	w := NewWriter(&writer)
+	w.BlockSize = 500
+	w.Indexed = true
+	w.CheckpointInterval = 10
+	if _, err := w.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressed := writer.Bytes()
+
+	reader, err := NewBlockReader(bytes.NewReader(compressed), int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for offset := 0; offset < len(original); offset++ {
+		for _, length := range []int{1, 2, 3, 7} {
+			if offset+length > len(original) {
+				continue
+			}
+			p := make([]byte, length)
+			if _, err := reader.ReadAt(p, int64(offset)); err != nil {
+				t.Fatalf("failed to read %d bytes at %d: %v", length, offset, err)
+			}
+			if !bytes.Equal(p, original[offset:offset+length]) {
+				t.Fatalf("Expected %q at %d but received %q", original[offset:offset+length], offset, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package huffman
+
+// Streams written before the versioned header was introduced have no magic
+// bytes. They consist of:
+//   - the pre-order traversal of the tree, where internal nodes are a zero bit
+//     and leaves are a one bit followed by the leaf's UTF-8 character
+//   - CONTROL_CHAR
+//   - one bits up to the next byte boundary
+//   - the coded data, padded with one bits
+//
+// Nothing records where the data ends, so the padding of the final byte can
+// decode into extra characters; such streams are read exactly as the original
+// decoder read them.
+
+// CONTROL_CHAR terminated the tree of legacy streams
+const CONTROL_CHAR rune = '⁂'
+
+// isLegacy reports whether a stream beginning with b is a legacy stream. The
+// pre-order traversal begins with the zero bit of the root, while the magic
+// bytes begin with a one bit.
+func isLegacy(b byte) bool {
+	return b&0x80 == 0
+}
+
+// readLegacyHeader rebuilds the tree of a legacy stream and consumes the
+// CONTROL_CHAR and padding that follow it
+func (hf *HuffmanTree) readLegacyHeader(r *BitReader) error {
+	var traverse func(n *FrequencyNode) error
+
+	readChild := func() (*FrequencyNode, error) {
+		bit, err := r.ReadBit()
+		if err != nil {
+			return nil, err
+		}
+
+		if bit == One {
+			char, _, err := r.ReadRune()
+			if err != nil {
+				return nil, err
+			}
+			return &FrequencyNode{char: char}, nil
+		}
+
+		child := &FrequencyNode{}
+		if err := traverse(child); err != nil {
+			return nil, err
+		}
+		return child, nil
+	}
+
+	traverse = func(n *FrequencyNode) error {
+		left, err := readChild()
+		if err != nil {
+			return err
+		}
+
+		right, err := readChild()
+		if err != nil {
+			return err
+		}
+
+		n.left = left
+		n.right = right
+
+		return nil
+	}
+
+	if bit, err := r.ReadBit(); bit != Zero || err != nil {
+		return ErrHeader
+	}
+	hf.root = &FrequencyNode{}
+	if err := traverse(hf.root); err != nil {
+		return ErrHeader
+	}
+
+	if char, _, err := r.ReadRune(); char != CONTROL_CHAR || err != nil {
+		return ErrHeader
+	}
+
+	// Resetting here clears any padded bits following the header control
+	// character, which ensures reading compressed file begins at the correct
+	// location
+	r.Reset()
+
+	return nil
+}
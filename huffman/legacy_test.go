@@ -0,0 +1,61 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestLegacyStream(t *testing.T) {
+	// legacy-test.huf was written from legacy-test.txt by the original,
+	// headerless encoder
+	original, err := os.ReadFile("legacy-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, err := os.ReadFile("legacy-test.huf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reader.IsLegacy() {
+		t.Error("Expected the stream to be detected as legacy")
+	}
+
+	decompressed := make([]byte, 0)
+	buf := make([]byte, 7)
+	for {
+		n, err := reader.Read(buf)
+		decompressed = append(decompressed, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+
+	if !bytes.Equal(original, decompressed) {
+		t.Errorf("Expected %q but received %q", original, decompressed)
+	}
+
+	current := compress(t, original, Header{})
+
+	reader, err = NewReader(bytes.NewReader(current))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.IsLegacy() {
+		t.Error("Expected the stream not to be detected as legacy")
+	}
+
+	// Plain text begins with a zero bit too, but is no valid legacy stream
+	if _, err := NewReader(bytes.NewReader(original)); !errors.Is(err, ErrHeader) {
+		t.Errorf("Expected %v for plain text but received %v", ErrHeader, err)
+	}
+}
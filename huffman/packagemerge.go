@@ -0,0 +1,120 @@
+package huffman
+
+import (
+	"fmt"
+	"sort"
+)
+
+// packageItem is either a single leaf or a package of two items from the
+// previous round of package-merge
+type packageItem struct {
+	weight int
+	leaf   *FrequencyNode
+	left   *packageItem
+	right  *packageItem
+}
+
+// LimitedCodeLengths computes optimal code lengths for the given leaves (e.g.
+// the result of FrequencyTable.ToList) under the constraint that no code is
+// longer than limit bits, using the package-merge algorithm.
+//
+// Package-merge views a code of length l as l "coins" of the symbol's weight,
+// one per level of the tree. Starting from the deepest level, every round
+// pairs the cheapest items of the previous round into packages and merges
+// them with a fresh copy of the leaves; after limit rounds the cheapest
+// 2n-2 items are taken and each symbol's code length is the number of times
+// its leaf occurs within them.
+func LimitedCodeLengths(leaves []*FrequencyNode, limit int) (map[rune]int, error) {
+	lengths := make(map[rune]int)
+
+	if len(leaves) <= 1 {
+		for _, leaf := range leaves {
+			lengths[leaf.char] = 0
+		}
+		return lengths, nil
+	}
+
+	if limit < 1 || limit > maxCodeLength || (limit < 63 && len(leaves) > 1<<limit) {
+		return nil, fmt.Errorf("cannot code %d symbols with codes of at most %d bits", len(leaves), limit)
+	}
+
+	sorted := make([]*packageItem, len(leaves))
+	for i, leaf := range leaves {
+		sorted[i] = &packageItem{weight: leaf.freq, leaf: leaf}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.weight != b.weight {
+			return a.weight < b.weight
+		}
+		return a.leaf.char < b.leaf.char
+	})
+
+	items := sorted
+	for level := 1; level < limit; level++ {
+		packages := make([]*packageItem, 0, len(items)/2)
+		for i := 0; i+1 < len(items); i += 2 {
+			packages = append(packages, &packageItem{
+				weight: items[i].weight + items[i+1].weight,
+				left:   items[i],
+				right:  items[i+1],
+			})
+		}
+
+		// Merge the packages with the leaves, preferring leaves on ties
+		merged := make([]*packageItem, 0, len(sorted)+len(packages))
+		i, j := 0, 0
+		for i < len(sorted) || j < len(packages) {
+			if j == len(packages) || (i < len(sorted) && sorted[i].weight <= packages[j].weight) {
+				merged = append(merged, sorted[i])
+				i += 1
+			} else {
+				merged = append(merged, packages[j])
+				j += 1
+			}
+		}
+
+		items = merged
+	}
+
+	var count func(item *packageItem)
+	count = func(item *packageItem) {
+		if item.leaf != nil {
+			lengths[item.leaf.char] += 1
+			return
+		}
+		count(item.left)
+		count(item.right)
+	}
+
+	for _, item := range items[:2*len(leaves)-2] {
+		count(item)
+	}
+
+	return lengths, nil
+}
+
+// buildTree returns the canonical tree for the frequencies in ft. The
+// unrestricted Huffman tree is optimal, so package-merge only replaces it when
+// one of its codes exceeds a non-zero limit.
+func buildTree(ft *FrequencyTable, limit int) (*HuffmanTree, error) {
+	pq := NewPriorityQueue(ft.ToList())
+
+	tree := NewHuffmanTree(pq.ToBinaryTree()).Canonical()
+
+	if limit == 0 {
+		return tree, nil
+	}
+
+	for _, length := range tree.CodeLengths() {
+		if length > limit {
+			lengths, err := LimitedCodeLengths(ft.ToList(), limit)
+			if err != nil {
+				return nil, err
+			}
+			return NewCanonicalHuffmanTree(lengths)
+		}
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,84 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fibonacciLeaves returns n leaves whose frequencies follow the Fibonacci
+// sequence, which produces the deepest possible Huffman tree
+func fibonacciLeaves(n int) []*FrequencyNode {
+	leaves := make([]*FrequencyNode, 0, n)
+	a, b := 1, 1
+	for i := 0; i < n; i++ {
+		leaves = append(leaves, &FrequencyNode{char: rune('A' + i), freq: a})
+		a, b = b, a+b
+	}
+	return leaves
+}
+
+func cost(leaves []*FrequencyNode, lengths map[rune]int) int {
+	total := 0
+	for _, leaf := range leaves {
+		total += leaf.freq * lengths[leaf.char]
+	}
+	return total
+}
+
+func TestLimitedCodeLengths(t *testing.T) {
+	leaves := fibonacciLeaves(20)
+
+	unlimited := NewHuffmanTree(NewPriorityQueue(fibonacciLeaves(20)).ToBinaryTree()).CodeLengths()
+
+	if unlimited['A'] != 19 {
+		t.Fatalf("Expected the unrestricted tree to be 19 levels deep, but received %d", unlimited['A'])
+	}
+
+	for _, limit := range []int{5, 8, 12, 19, 30} {
+		lengths, err := LimitedCodeLengths(leaves, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kraft := 0.0
+		for _, leaf := range leaves {
+			length := lengths[leaf.char]
+			if length < 1 || length > limit {
+				t.Errorf("Expected code length of %q to be between 1 and %d, but received %d", leaf.char, limit, length)
+			}
+			kraft += 1 / float64(uint64(1)<<length)
+		}
+
+		if kraft != 1 {
+			t.Errorf("Expected a complete code with a Kraft sum of 1 for limit %d, but received %f", limit, kraft)
+		}
+
+		// A limit the unrestricted tree already satisfies must cost nothing
+		if limit >= 19 && cost(leaves, lengths) != cost(leaves, unlimited) {
+			t.Errorf("Expected limit %d to match the optimal cost %d, but received %d", limit, cost(leaves, unlimited), cost(leaves, lengths))
+		}
+
+		if _, err := NewCanonicalHuffmanTree(lengths); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := LimitedCodeLengths(leaves, 4); err == nil {
+		t.Error("Expected 20 symbols not to fit in 4-bit codes")
+	}
+}
+
+func TestCompressionMaxCodeLength(t *testing.T) {
+	original := make([]byte, 0)
+	for i, leaf := range fibonacciLeaves(20) {
+		original = append(original, bytes.Repeat([]byte{byte('A' + i)}, leaf.freq)...)
+	}
+
+	for _, limit := range []int{0, 6, 8, 12} {
+		decompressed := roundTrip(t, original, Header{MaxCodeLength: limit})
+
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("Expected decompressed to be identical to original with a limit of %d", limit)
+		}
+	}
+}
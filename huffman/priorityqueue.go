@@ -0,0 +1,150 @@
+package huffman
+
+import (
+	"fmt"
+	"io"
+)
+
+func NewPriorityQueue(list []*FrequencyNode) *PriorityQueue {
+	pq := &PriorityQueue{
+		nodes: make([]*FrequencyNode, 0),
+	}
+
+	for _, node := range list {
+		pq.Insert(node)
+	}
+
+	return pq
+}
+
+type PriorityQueue struct {
+	nodes []*FrequencyNode
+}
+
+func (pq *PriorityQueue) Insert(val *FrequencyNode) {
+	pq.nodes = append(pq.nodes, val)
+	pq.Up(len(pq.nodes) - 1)
+}
+
+func (pq *PriorityQueue) Pop() *FrequencyNode {
+	if len(pq.nodes) == 0 {
+		return nil
+	}
+
+	out := pq.nodes[0]
+
+	if len(pq.nodes) == 1 {
+		pq.nodes = []*FrequencyNode{}
+		return out
+	}
+
+	last := pq.nodes[len(pq.nodes)-1]
+
+	pq.nodes[0] = last
+	pq.nodes = pq.nodes[:len(pq.nodes)-1]
+
+	pq.Down(0)
+
+	return out
+}
+
+func (pq *PriorityQueue) Up(idx int) {
+	if idx == 0 {
+		return
+	}
+
+	parentIdx := pq.parentIdx(idx)
+
+	if pq.nodes[idx].less(pq.nodes[parentIdx]) {
+		pq.nodes[idx], pq.nodes[parentIdx] = pq.nodes[parentIdx], pq.nodes[idx]
+		pq.Up(parentIdx)
+	}
+}
+
+func (pq *PriorityQueue) Down(idx int) {
+	leftIdx := pq.leftIdx(idx)
+
+	// Given Heaps are complete from left to right, if the left child's index
+	// is at or beyond the length of the heap, then we cannot continue.
+	if leftIdx >= len(pq.nodes) {
+		return
+	}
+
+	// The smaller child, which the right one can only be if it is present
+	smallest := leftIdx
+	if rightIdx := pq.rightIdx(idx); rightIdx < len(pq.nodes) && pq.nodes[rightIdx].less(pq.nodes[leftIdx]) {
+		smallest = rightIdx
+	}
+
+	if pq.nodes[smallest].less(pq.nodes[idx]) {
+		pq.nodes[idx], pq.nodes[smallest] = pq.nodes[smallest], pq.nodes[idx]
+		pq.Down(smallest)
+	}
+}
+
+func (pq *PriorityQueue) parentIdx(idx int) int {
+	return (idx - 1) / 2
+}
+
+func (pq *PriorityQueue) leftIdx(idx int) int {
+	return (2 * idx) + 1
+}
+
+func (pq *PriorityQueue) rightIdx(idx int) int {
+	return (2 * idx) + 2
+}
+
+// Log writes a graphviz (dot) representation of the heap to w
+func (pq *PriorityQueue) Log(w io.Writer) error {
+	definitions := ""
+	connections := ""
+
+	for idx, node := range pq.nodes {
+		definitions += fmt.Sprintf(` node_%d[label="char: %q\nrune: %d\nfreq: %d"];`, idx, node.char, node.char, node.freq)
+		leftIdx := pq.leftIdx(idx)
+		rightIdx := pq.rightIdx(idx)
+		if leftIdx < len(pq.nodes) {
+			connections += fmt.Sprintf(` node_%d -- node_%d;`, idx, leftIdx)
+		}
+		if rightIdx < len(pq.nodes) {
+			connections += fmt.Sprintf(` node_%d -- node_%d;`, idx, rightIdx)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `graph {
+		%s
+		%s
+	}`, definitions, connections)
+
+	return err
+}
+
+// ToBinaryTree merges the nodes into a Huffman tree and returns its root. A
+// single node is its own root, whose symbol then has an empty code, and an
+// empty queue has no tree at all, so nil is returned.
+func (pq *PriorityQueue) ToBinaryTree() *FrequencyNode {
+	var root *FrequencyNode
+
+	for len(pq.nodes) > 0 {
+		if len(pq.nodes) == 1 {
+			root = pq.nodes[0]
+			break
+		}
+
+		a := pq.Pop()
+		b := pq.Pop()
+		c := &FrequencyNode{
+			freq:  a.freq + b.freq,
+			first: a.smallest(),
+			left:  a,
+			right: b,
+		}
+		if b.smallest() < c.first {
+			c.first = b.smallest()
+		}
+
+		pq.Insert(c)
+	}
+
+	return root
+}
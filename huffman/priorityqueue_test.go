@@ -0,0 +1,73 @@
+package huffman
+
+import (
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	f, err := os.Open("frequency-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ft := NewFrequencyTable(Runes)
+
+	if err := ft.Populate(f); err != nil {
+		t.Errorf("failed to populate table: %v", err)
+	}
+
+	nodes := ft.ToList()
+
+	pq := NewPriorityQueue(nodes)
+
+	previousNode := pq.Pop()
+	counter := 0
+	var currentNode *FrequencyNode
+	for {
+		currentNode = pq.Pop()
+		counter += 1
+
+		if currentNode == nil {
+			break
+		}
+
+		if currentNode.freq < previousNode.freq || (currentNode.freq == previousNode.freq && currentNode.char < previousNode.char) {
+			t.Errorf("expected %q to have a higher frequency than %q", currentNode.char, previousNode.char)
+		}
+
+		previousNode = currentNode
+	}
+}
+
+func TestDeterministicTree(t *testing.T) {
+	// Equal weights throughout, so that internal nodes tie with each other
+	// and with leaves, NUL among them
+	frequencies := map[rune]int{0: 1, 'a': 2, 'b': 3, 'c': 3, 'd': 2, 'e': 2, 'f': 1, 'g': 3, 'h': 3, 'i': 2, 'j': 2}
+
+	list := func() []*FrequencyNode {
+		nodes := make([]*FrequencyNode, 0, len(frequencies))
+		for s, freq := range frequencies {
+			nodes = append(nodes, &FrequencyNode{char: s, freq: freq})
+		}
+		return nodes
+	}
+
+	expected := NewHuffmanTree(NewPriorityQueue(list()).ToBinaryTree()).CodeLengths()
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		nodes := list()
+		random.Shuffle(len(nodes), func(i, j int) {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		})
+
+		lengths := NewHuffmanTree(NewPriorityQueue(nodes).ToBinaryTree()).CodeLengths()
+		if !reflect.DeepEqual(expected, lengths) {
+			t.Fatalf("Expected the code lengths %v regardless of the order of the leaves but received %v", expected, lengths)
+		}
+	}
+}
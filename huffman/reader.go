@@ -0,0 +1,388 @@
+package huffman
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	ErrTrailingData = errors.New("huffman: unexpected data after end of stream")
+	ErrChecksum     = errors.New("huffman: invalid checksum")
+	ErrSize         = errors.New("huffman: decoded size does not match header")
+)
+
+// symbolDecoder decodes the next symbols of a block, up to max of them, and
+// appends their bytes to p. It returns the extended slice along with the number
+// of symbols decoded.
+type symbolDecoder interface {
+	decode(b *BitReader, alphabet Alphabet, p []byte, max uint64) ([]byte, uint64, error)
+}
+
+// Reader is an io.Reader that decodes the Huffman-coded stream produced by a
+// Writer
+type Reader struct {
+	Header
+	input        *bufio.Reader
+	count        countingReader
+	version      byte
+	decoder      symbolDecoder
+	end          bool
+	bits         *BitReader
+	remaining    uint64
+	blockLength  uint64
+	blockDecoded uint64
+	dataLength   uint64
+	index        []indexEntry
+	codebooks    []*SharedCodebook
+	codebook     *SharedCodebook
+	digest       uint32
+	size         uint64
+	legacy       bool
+	bitReader    *BitReader
+	decoderTable map[string]rune
+	code         string
+	buf          []byte
+	err          error
+}
+
+// NewReader creates a new Reader reading the given reader. The header is read
+// immediately so that a malformed stream is reported here rather than on the
+// first Read. Streams with Shared codes are decoded with whichever of the
+// codebooks they name, or ErrCodebook is returned if none of them match.
+func NewReader(r io.Reader, codebooks ...*SharedCodebook) (*Reader, error) {
+	z := &Reader{codebooks: codebooks}
+	if err := z.Reset(r); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// Reset discards the Reader's state and makes it equivalent to the result of
+// NewReader, but reading from r instead. The codebooks are kept.
+func (z *Reader) Reset(r io.Reader) error {
+	*z = Reader{
+		count:     countingReader{r: r},
+		codebooks: z.codebooks,
+	}
+	z.input = bufio.NewReader(&z.count)
+
+	if head, err := z.input.Peek(1); err == nil && isLegacy(head[0]) {
+		return z.resetLegacy()
+	}
+
+	version, err := z.Header.read(z.input)
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	z.version = version
+
+	if z.Coding == Shared {
+		if z.codebook, err = findCodebook(z.Header.Codebook, z.codebooks); err != nil {
+			return err
+		}
+		if z.codebook.Alphabet != z.Alphabet {
+			return fmt.Errorf("failed to read header: %w", ErrHeader)
+		}
+	}
+
+	if z.version == 1 {
+		// The whole stream is a single block of Size bytes
+		z.blockLength = z.Size
+		return z.readBlock()
+	}
+
+	return z.nextBlock()
+}
+
+// nextBlock reads the length of the next block and, unless it marks the end of
+// the blocks, the rest of its header
+func (z *Reader) nextBlock() error {
+	// Indexed streams record where each block begins, including the end of
+	// the blocks
+	if z.Indexed {
+		z.index = append(z.index, indexEntry{
+			offset: uint64(z.count.n) - uint64(z.input.Buffered()),
+			start:  z.size,
+		})
+	}
+
+	length, err := binary.ReadUvarint(z.input)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read block length: %w", err)
+	}
+
+	if length == 0 {
+		z.end = true
+		return nil
+	}
+
+	if length > uint64(z.BlockSize) {
+		return fmt.Errorf("block of %d bytes exceeds the block size of %d bytes", length, z.BlockSize)
+	}
+	z.blockLength = length
+
+	return z.readBlock()
+}
+
+// readBlock reads the tree, symbol count and data length that precede the
+// coded data of a block and prepares to decode it
+func (z *Reader) readBlock() error {
+	switch z.Coding {
+	case Static:
+		tree := &HuffmanTree{}
+
+		if err := tree.ReadHeader(NewBitReader(z.input), z.Alphabet); err != nil {
+			return fmt.Errorf("failed to read header: %v", err)
+		}
+
+		if z.MaxCodeLength > 0 {
+			for s, length := range tree.CodeLengths() {
+				if length > z.MaxCodeLength {
+					return fmt.Errorf("code length of %q exceeds the maximum of %d bits", s, z.MaxCodeLength)
+				}
+			}
+		}
+
+		z.decoder = newDecodeTable(tree)
+	case Adaptive:
+		z.decoder = NewAdaptiveHuffmanTree(z.Alphabet)
+	case Shared:
+		z.decoder = z.codebook.table
+	case Context:
+		decoder, err := readContextCodes(NewBitReader(z.input), z.Alphabet, z.MaxCodeLength)
+		if err != nil {
+			return fmt.Errorf("failed to read header: %v", err)
+		}
+		z.decoder = decoder
+	}
+
+	remaining, err := binary.ReadUvarint(z.input)
+	if err != nil {
+		return fmt.Errorf("failed to read symbol count: %v", err)
+	}
+
+	// Every symbol takes at least a byte, so a block holds no more symbols than
+	// bytes. Checking here stops a zero-length code from decoding a corrupt
+	// count without end.
+	if remaining > z.blockLength {
+		return fmt.Errorf("block of %d bytes cannot hold %d symbols: %w", z.blockLength, remaining, ErrSize)
+	}
+	z.remaining = remaining
+
+	dataLength, err := binary.ReadUvarint(z.input)
+	if err != nil {
+		return fmt.Errorf("failed to read data length: %v", err)
+	}
+	z.bits = NewBitReader(io.LimitReader(z.input, int64(dataLength)))
+	z.dataLength = dataLength
+
+	z.blockDecoded = 0
+
+	return nil
+}
+
+// resetLegacy prepares the Reader for a stream without magic bytes (see
+// legacy.go). Such streams are always UTF-8 characters without a checksum.
+func (z *Reader) resetLegacy() error {
+	z.legacy = true
+	z.Header = Header{
+		Alphabet: Runes,
+		Checksum: NoChecksum,
+	}
+	z.bitReader = NewBitReader(z.input)
+
+	tree := &HuffmanTree{}
+
+	if err := tree.readLegacyHeader(z.bitReader); err != nil {
+		return fmt.Errorf("failed to read legacy header: %w", err)
+	}
+
+	encoderTable := tree.ToLookupTable()
+	z.decoderTable = make(map[string]rune)
+	for r, c := range encoderTable {
+		z.decoderTable[c] = r
+	}
+
+	return nil
+}
+
+// IsLegacy reports whether the stream predates the versioned header, in which
+// case the Header holds assumed rather than recorded settings
+func (z *Reader) IsLegacy() bool {
+	return z.legacy
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.buf) < len(p) && z.err == nil {
+		if z.legacy {
+			z.err = z.decodeLegacySymbol()
+		} else {
+			z.err = z.decodeSymbols()
+		}
+	}
+
+	if len(z.buf) > 0 || len(p) == 0 {
+		n := copy(p, z.buf)
+		z.buf = z.buf[:copy(z.buf, z.buf[n:])]
+		return n, nil
+	}
+
+	return 0, z.err
+}
+
+// decodeSymbols buffers the bytes of the symbols resolved by the next table
+// lookup, moving on to the next block once every symbol of the current one has
+// been decoded
+func (z *Reader) decodeSymbols() error {
+	for z.remaining == 0 {
+		if z.end {
+			return z.checkEnd()
+		}
+		if err := z.endBlock(); err != nil {
+			return err
+		}
+	}
+
+	n := len(z.buf)
+
+	buf, count, err := z.decoder.decode(z.bits, z.Alphabet, z.buf, z.remaining)
+	if err != nil {
+		return err
+	}
+
+	z.buf = buf
+	z.digest = crc32.Update(z.digest, crc32.IEEETable, z.buf[n:])
+	z.size += uint64(len(z.buf) - n)
+	z.blockDecoded += uint64(len(z.buf) - n)
+	z.remaining -= count
+
+	if z.blockDecoded > z.blockLength {
+		return ErrSize
+	}
+
+	return nil
+}
+
+// endBlock verifies that only zero padding follows the symbols of the current
+// block and that they decoded to the block's length before reading the next
+// block
+func (z *Reader) endBlock() error {
+	if err := finishData(z.bits); err != nil {
+		return err
+	}
+
+	if z.blockDecoded != z.blockLength {
+		return ErrSize
+	}
+
+	if z.version == 1 {
+		z.end = true
+		return nil
+	}
+
+	return z.nextBlock()
+}
+
+// decodeLegacySymbol reads bits until they form a known code and buffers the
+// decoded character. Legacy streams are decoded until the input runs out.
+func (z *Reader) decodeLegacySymbol() error {
+	for {
+		bit, err := z.bitReader.ReadBit()
+		if err != nil {
+			return err
+		}
+		if bit == Zero {
+			z.code += "0"
+		} else {
+			z.code += "1"
+		}
+		if r, hasChar := z.decoderTable[z.code]; hasChar {
+			z.buf = z.Alphabet.appendSymbol(z.buf, r)
+			z.code = ""
+			return nil
+		}
+	}
+}
+
+// checkEnd verifies that the size and checksum match the original data and that
+// nothing follows the trailer. It returns io.EOF if so.
+func (z *Reader) checkEnd() error {
+	if z.version > 1 {
+		size, err := binary.ReadUvarint(z.input)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		z.Size = size
+	}
+
+	if z.size != z.Size {
+		return ErrSize
+	}
+
+	if z.Checksum == CRC32 {
+		var digest uint32
+		if err := binary.Read(z.input, binary.BigEndian, &digest); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if digest != z.digest {
+			return ErrChecksum
+		}
+	}
+
+	if z.Indexed {
+		return z.checkIndex()
+	}
+
+	if _, err := z.input.ReadByte(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return ErrTrailingData
+	}
+
+	return io.EOF
+}
+
+// checkIndex verifies that the rest of the input is an index of the blocks
+// that were read. It returns io.EOF if so.
+func (z *Reader) checkIndex() error {
+	// The index is a few bytes per block, so it is read whole
+	p, err := io.ReadAll(z.input)
+	if err != nil {
+		return err
+	}
+
+	index, err := readIndex(p)
+	if err != nil {
+		return err
+	}
+
+	if len(index) != len(z.index) {
+		return ErrIndex
+	}
+	for i := range index {
+		if index[i].offset != z.index[i].offset || index[i].start != z.index[i].start {
+			return ErrIndex
+		}
+	}
+
+	return io.EOF
+}
+
+// Close does not close the underlying reader
+func (z *Reader) Close() error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// SymbolStats describes the code a symbol receives
+type SymbolStats struct {
+	// Symbol is the text of the symbol as a Go string literal without the
+	// quotes, so that escaped bytes and control characters stay readable
+	Symbol      string  `json:"symbol"`
+	Count       int     `json:"count"`
+	Probability float64 `json:"probability"`
+	CodeLength  int     `json:"code_length"`
+	Code        string  `json:"code"`
+}
+
+// Stats describes how closely the Huffman code for a FrequencyTable approaches
+// the entropy of its symbols. Lengths are in bits per symbol.
+type Stats struct {
+	// Symbols is ordered by decreasing count, then by symbol
+	Symbols []SymbolStats `json:"symbols"`
+	// Total is the number of symbols and Size the number of bytes they take
+	Total int    `json:"total"`
+	Size  uint64 `json:"size"`
+	// Entropy is the Shannon entropy, the least average length of any code
+	Entropy           float64 `json:"entropy"`
+	AverageCodeLength float64 `json:"average_code_length"`
+	// Redundancy is the average code length in excess of the entropy
+	Redundancy float64 `json:"redundancy"`
+	// KraftSum is the sum of 2^-length over the codes, which is 1 for a
+	// complete prefix code
+	KraftSum float64 `json:"kraft_sum"`
+	// PredictedSize is the size in bytes of the stream a Writer with static
+	// codes writes, headers and trailer included. For tables from
+	// NewBlockFrequencyTable it codes every block with a tree of its own at
+	// the table's block size; other tables are coded as a single block, as a
+	// Writer whose BlockSize is at least Size codes them.
+	PredictedSize uint64 `json:"predicted_size"`
+	MaxCodeLength int    `json:"max_code_length"`
+	MinCodeLength int    `json:"min_code_length"`
+}
+
+// Stats builds the code that a Writer limited to maxCodeLength bits (0 for no
+// limit) would use for the counted symbols and describes it
+func (ft *FrequencyTable) Stats(maxCodeLength int) (*Stats, error) {
+	stats := &Stats{
+		Symbols: make([]SymbolStats, 0, len(ft.table)),
+		Total:   ft.Total(),
+	}
+
+	var symbolBytes []byte
+
+	var codes map[rune]string
+	if stats.Total > 0 {
+		tree, err := buildTree(ft, maxCodeLength)
+		if err != nil {
+			return nil, err
+		}
+		codes = tree.ToLookupTable()
+	}
+
+	for s, count := range ft.table {
+		symbolBytes = ft.alphabet.appendSymbol(symbolBytes[:0], s)
+		stats.Size += uint64(count * len(symbolBytes))
+
+		p := float64(count) / float64(stats.Total)
+		length := len(codes[s])
+
+		stats.Symbols = append(stats.Symbols, SymbolStats{
+			Symbol:      quote(symbolBytes),
+			Count:       count,
+			Probability: p,
+			CodeLength:  length,
+			Code:        codes[s],
+		})
+
+		stats.Entropy -= p * math.Log2(p)
+		stats.AverageCodeLength += p * float64(length)
+		stats.KraftSum += math.Pow(2, -float64(length))
+
+		if length > stats.MaxCodeLength {
+			stats.MaxCodeLength = length
+		}
+		if len(stats.Symbols) == 1 || length < stats.MinCodeLength {
+			stats.MinCodeLength = length
+		}
+	}
+	stats.Redundancy = stats.AverageCodeLength - stats.Entropy
+
+	sort.Slice(stats.Symbols, func(i, j int) bool {
+		a, b := stats.Symbols[i], stats.Symbols[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	blocks, blockSize := ft.blocks, uint64(ft.blockSize)
+	if blockSize == 0 {
+		blocks, blockSize = []*FrequencyTable{ft}, uint64(DefaultBlockSize)
+		if stats.Size > blockSize {
+			blockSize = stats.Size
+		}
+	}
+
+	// The stream header, the blocks, the end marker, the size and the CRC-32
+	size := uint64(len(magic)+3) + uvarintLength(blockSize)
+	for _, block := range blocks {
+		length, err := block.predictBlock(maxCodeLength)
+		if err != nil {
+			return nil, err
+		}
+		size += length
+	}
+	stats.PredictedSize = size + uvarintLength(0) + uvarintLength(stats.Size) + 4
+
+	return stats, nil
+}
+
+// predictBlock returns the size in bytes of the block in which a Writer codes
+// the counted symbols with a static code: its length, the tree, the symbol
+// count, the data length and the data. There is no block without symbols.
+func (ft *FrequencyTable) predictBlock(maxCodeLength int) (uint64, error) {
+	total := ft.Total()
+	if total == 0 {
+		return 0, nil
+	}
+
+	tree, err := buildTree(ft, maxCodeLength)
+	if err != nil {
+		return 0, err
+	}
+	var treeHeader bytes.Buffer
+	if err := tree.WriteHeader(NewBitWriter(&treeHeader), ft.alphabet); err != nil {
+		return 0, err
+	}
+
+	var symbolBytes []byte
+	var length, dataBits uint64
+	codes := tree.ToLookupTable()
+	for s, count := range ft.table {
+		symbolBytes = ft.alphabet.appendSymbol(symbolBytes[:0], s)
+		length += uint64(count * len(symbolBytes))
+		dataBits += uint64(count * len(codes[s]))
+	}
+
+	dataBytes := (dataBits + 7) / 8
+	return uvarintLength(length) + uint64(treeHeader.Len()) + uvarintLength(uint64(total)) + uvarintLength(dataBytes) + dataBytes, nil
+}
+
+func uvarintLength(v uint64) uint64 {
+	buf := [binary.MaxVarintLen64]byte{}
+	return uint64(binary.PutUvarint(buf[:], v))
+}
+
+// quote returns p as a Go string literal without the quotes
+func quote(p []byte) string {
+	quoted := strconv.Quote(string(p))
+	return quoted[1 : len(quoted)-1]
+}
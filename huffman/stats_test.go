@@ -0,0 +1,90 @@
+package huffman
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	original, err := os.ReadFile("frequency-test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{0, 5} {
+		ft := NewFrequencyTable(Runes)
+		if err := ft.Populate(bytes.NewReader(original)); err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := ft.Stats(limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stats.Size != uint64(len(original)) || stats.Total != ft.Total() {
+			t.Errorf("Expected %d symbols in %d bytes but received %d in %d", ft.Total(), len(original), stats.Total, stats.Size)
+		}
+
+		// A Huffman code is complete and within a bit of the entropy, while a
+		// limited one can only do worse
+		if math.Abs(stats.KraftSum-1) > 1e-9 {
+			t.Errorf("Expected a Kraft sum of 1 but received %f", stats.KraftSum)
+		}
+		if stats.Redundancy < 0 || (limit == 0 && stats.Redundancy >= 1) {
+			t.Errorf("Expected an average code length within a bit of the entropy of %f but received %f", stats.Entropy, stats.AverageCodeLength)
+		}
+		if limit > 0 && stats.MaxCodeLength > limit {
+			t.Errorf("Expected codes of at most %d bits but received %d", limit, stats.MaxCodeLength)
+		}
+
+		probability := 0.0
+		for i, s := range stats.Symbols {
+			if i > 0 && stats.Symbols[i-1].Count < s.Count {
+				t.Errorf("Expected symbols by decreasing count but %q follows %q", s.Symbol, stats.Symbols[i-1].Symbol)
+			}
+			if len(s.Code) != s.CodeLength || s.CodeLength < stats.MinCodeLength || s.CodeLength > stats.MaxCodeLength {
+				t.Errorf("Expected the code %q of %q to be %d bits long", s.Code, s.Symbol, s.CodeLength)
+			}
+			probability += s.Probability
+		}
+		if math.Abs(probability-1) > 1e-9 {
+			t.Errorf("Expected the probabilities to sum to 1 but received %f", probability)
+		}
+
+		compressed := compress(t, original, Header{MaxCodeLength: limit})
+		if stats.PredictedSize != uint64(len(compressed)) {
+			t.Errorf("Expected a predicted size of %d bytes but received %d", len(compressed), stats.PredictedSize)
+		}
+	}
+
+	// Each block has a tree of its own, split wherever the next rune would
+	// not fit, and a large block size takes a longer uvarint in the header
+	text := bytes.Repeat(original, 20)
+	for _, blockSize := range []int{0, 100, 1001, 300000000} {
+		ft := NewBlockFrequencyTable(Runes, blockSize)
+		if err := ft.Populate(bytes.NewReader(text)); err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := ft.Stats(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compressed := compress(t, text, Header{BlockSize: blockSize, MaxCodeLength: 5})
+		if stats.PredictedSize != uint64(len(compressed)) {
+			t.Errorf("Expected a predicted size of %d bytes with a block size of %d but received %d", len(compressed), blockSize, stats.PredictedSize)
+		}
+	}
+
+	empty, err := NewFrequencyTable(Bytes).Stats(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressed := compress(t, nil, Header{Alphabet: Bytes}); empty.PredictedSize != uint64(len(compressed)) {
+		t.Errorf("Expected a predicted size of %d bytes for no data but received %d", len(compressed), empty.PredictedSize)
+	}
+}
@@ -0,0 +1,475 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"runtime"
+)
+
+var ErrClosed = errors.New("huffman: writer is closed")
+
+// Writer is an io.WriteCloser that Huffman-encodes everything written to it.
+// The input is split into blocks of up to BlockSize bytes, each coded with a
+// tree built from its own frequencies. Blocks are coded concurrently and
+// written in order, so at most Concurrency blocks are held in memory and
+// nothing reaches the underlying writer until the first block is coded or
+// the Writer is closed.
+type Writer struct {
+	Header
+	// Concurrency is the most blocks coded at once, each on its own
+	// goroutine; the zero value uses runtime.GOMAXPROCS(0). Like the Header,
+	// it must be set before the first call to Write.
+	Concurrency int
+	// CheckpointInterval is the number of bytes of original data between the
+	// checkpoints an indexed stream records within each block, which bounds
+	// how much a BlockReader decodes to reach an offset; the zero value is
+	// DefaultCheckpointInterval
+	CheckpointInterval int
+	// SharedCodebook, if set, codes every block with the shared codebook
+	// rather than a tree of its own. The Header's Coding, Alphabet and
+	// Codebook are then taken from it.
+	SharedCodebook *SharedCodebook
+	w              *bufio.Writer
+	count          countingWriter
+	buf            bytes.Buffer
+	index          []indexEntry
+	written        uint64
+	pending        []*block
+	free           []*block
+	wroteHeader    bool
+	digest         uint32
+	closed         bool
+	err            error
+}
+
+// block is a block of input being coded by its own goroutine. done is closed
+// once output holds the coded block or err is set.
+type block struct {
+	input       []byte
+	data        bytes.Buffer
+	output      bytes.Buffer
+	checkpoints []checkpoint
+	err         error
+	done        chan struct{}
+}
+
+// NewWriter returns a new Writer. It is the caller's responsibility to call
+// Close on the Writer when done.
+func NewWriter(w io.Writer) *Writer {
+	z := &Writer{
+		count: countingWriter{w: w},
+	}
+	z.w = bufio.NewWriter(&z.count)
+	return z
+}
+
+// Reset discards the Writer's state and makes it equivalent to the result of
+// NewWriter, but writing to w instead
+func (z *Writer) Reset(w io.Writer) {
+	// Blocks still being coded must finish before they can be reused
+	for _, b := range z.pending {
+		<-b.done
+		z.free = append(z.free, b)
+	}
+
+	z.Header = Header{}
+	z.Concurrency = 0
+	z.CheckpointInterval = 0
+	z.SharedCodebook = nil
+	z.count = countingWriter{w: w}
+	z.w.Reset(&z.count)
+	z.buf.Reset()
+	z.index = z.index[:0]
+	z.written = 0
+	z.pending = z.pending[:0]
+	z.wroteHeader = false
+	z.digest = 0
+	z.closed = false
+	z.err = nil
+}
+
+// Write buffers p and starts coding every block it completes
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, ErrClosed
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+
+	if err := z.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	z.buf.Write(p)
+
+	for z.buf.Len() >= z.BlockSize {
+		// Blocks end on a symbol boundary so that characters are not split
+		// into escaped bytes, unless the block size is smaller than one
+		input := z.buf.Bytes()[:z.BlockSize]
+		n := z.Alphabet.boundary(input)
+		if n == 0 {
+			n = len(input)
+		}
+
+		if z.err = z.startBlock(z.buf.Next(n)); z.err != nil {
+			return 0, z.err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close codes the remaining input and writes the end of the stream to the
+// underlying writer. It does not close the underlying writer.
+//
+// The stream consists of the Header followed by blocks (see encodeBlock), a
+// zero block length marking the end of the blocks and the trailer:
+//   - the size of the original data as a uvarint
+//   - the checksum of the original data, unless Checksum is NoChecksum
+//   - the index of the blocks if Indexed is set (see writeIndex)
+func (z *Writer) Close() error {
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+
+	if z.err != nil {
+		return z.err
+	}
+
+	if err := z.writeHeader(); err != nil {
+		return err
+	}
+
+	if z.buf.Len() > 0 {
+		if err := z.startBlock(z.buf.Next(z.buf.Len())); err != nil {
+			return err
+		}
+	}
+
+	for len(z.pending) > 0 {
+		if err := z.writeBlock(); err != nil {
+			return err
+		}
+	}
+
+	// The final entry of the index locates the end of the blocks
+	z.addIndexEntry(0, nil)
+
+	if err := writeUvarint(z.w, 0); err != nil {
+		return fmt.Errorf("failed to write end of blocks: %v", err)
+	}
+
+	if err := writeUvarint(z.w, z.Size); err != nil {
+		return fmt.Errorf("failed to write size: %v", err)
+	}
+
+	if z.Checksum == CRC32 {
+		if err := binary.Write(z.w, binary.BigEndian, z.digest); err != nil {
+			return fmt.Errorf("failed to write checksum: %v", err)
+		}
+	}
+
+	if z.Indexed {
+		if err := writeIndex(z.w, z.index); err != nil {
+			return fmt.Errorf("failed to write index: %v", err)
+		}
+	}
+
+	z.buf.Reset()
+
+	return z.w.Flush()
+}
+
+// writeHeader fills in the default block size and writes the Header, once
+func (z *Writer) writeHeader() error {
+	if z.wroteHeader {
+		return nil
+	}
+	z.wroteHeader = true
+
+	if z.BlockSize == 0 {
+		z.BlockSize = DefaultBlockSize
+	}
+	z.Size = 0
+
+	if z.SharedCodebook != nil {
+		z.Coding = Shared
+		z.Alphabet = z.SharedCodebook.Alphabet
+		z.Codebook = z.SharedCodebook.ID
+	} else if z.Coding == Shared {
+		z.err = fmt.Errorf("shared codes need a SharedCodebook")
+		return z.err
+	}
+
+	if z.err = z.Header.write(z.w); z.err != nil {
+		z.err = fmt.Errorf("failed to write header: %v", z.err)
+	}
+
+	return z.err
+}
+
+// startBlock copies input into a block and codes it on a new goroutine, first
+// writing the oldest pending block if Concurrency blocks are already pending
+func (z *Writer) startBlock(input []byte) error {
+	concurrency := z.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	if len(z.pending) >= concurrency {
+		if err := z.writeBlock(); err != nil {
+			return err
+		}
+	}
+
+	var b *block
+	if n := len(z.free); n > 0 {
+		b, z.free = z.free[n-1], z.free[:n-1]
+	} else {
+		b = &block{}
+	}
+	b.input = append(b.input[:0], input...)
+	b.done = make(chan struct{})
+
+	// The checksum and size follow the order of the input, so they are
+	// updated here rather than by the goroutine
+	z.digest = crc32.Update(z.digest, crc32.IEEETable, input)
+	z.Size += uint64(len(input))
+
+	// Checkpoints are only of use to the index, and only some codes can be
+	// decoded from one
+	interval := 0
+	if z.Indexed && z.Coding.resumable() {
+		interval = z.CheckpointInterval
+		if interval <= 0 {
+			interval = DefaultCheckpointInterval
+		}
+	}
+
+	go func(header Header) {
+		b.err = encodeBlock(b, header, z.SharedCodebook, interval)
+		close(b.done)
+	}(z.Header)
+
+	z.pending = append(z.pending, b)
+
+	return nil
+}
+
+// writeBlock waits for the oldest pending block and writes it to the
+// underlying writer
+func (z *Writer) writeBlock() error {
+	b := z.pending[0]
+	<-b.done
+
+	z.pending = z.pending[:copy(z.pending, z.pending[1:])]
+	z.free = append(z.free, b)
+
+	if b.err != nil {
+		return b.err
+	}
+
+	z.addIndexEntry(uint64(len(b.input)), b.checkpoints)
+
+	_, err := b.output.WriteTo(z.w)
+	return err
+}
+
+// addIndexEntry records that a block of length bytes of original data begins
+// at the current offset, if the stream is indexed
+func (z *Writer) addIndexEntry(length uint64, checkpoints []checkpoint) {
+	if !z.Indexed {
+		return
+	}
+
+	z.index = append(z.index, indexEntry{
+		offset:      uint64(z.count.n) + uint64(z.w.Buffered()),
+		start:       z.written,
+		checkpoints: append([]checkpoint{}, checkpoints...),
+	})
+	z.written += length
+}
+
+// encodeBlock codes b.input, which must not be empty, with its own tree (or
+// the codebook for Shared codes) into b.output, recording checkpoints every
+// interval bytes. A block consists of:
+//   - the length of the original data of the block as a uvarint
+//   - the code lengths written by HuffmanTree.WriteHeader, for Static codes,
+//     or by contextCodes.write, for Context codes
+//   - the number of symbols as a uvarint
+//   - the length of the coded data in bytes as a uvarint
+//   - the coded data, padded with zeros to a whole byte
+func encodeBlock(b *block, header Header, codebook *SharedCodebook, interval int) error {
+	// The coded data is staged so that its length can precede it, which lets
+	// readers skip a block without decoding
+	b.data.Reset()
+	b.output.Reset()
+	b.checkpoints = nil
+
+	var tree *HuffmanTree
+	var codes *contextCodes
+	var symbols uint64
+
+	switch header.Coding {
+	case Static:
+		ft := NewFrequencyTable(header.Alphabet)
+
+		if err := ft.Populate(bytes.NewReader(b.input)); err != nil {
+			return fmt.Errorf("error populating frequency table: %v", err)
+		}
+
+		var err error
+		tree, err = buildTree(ft, header.MaxCodeLength)
+		if err != nil {
+			return fmt.Errorf("failed to build tree: %v", err)
+		}
+
+		b.checkpoints, err = encode(NewBitWriter(&b.data), b.input, header.Alphabet, tree.ToCodeTable(), interval)
+		if err != nil {
+			return err
+		}
+		symbols = uint64(ft.Total())
+	case Adaptive:
+		var err error
+		symbols, err = encodeAdaptive(NewBitWriter(&b.data), b.input, header.Alphabet)
+		if err != nil {
+			return err
+		}
+	case Shared:
+		var err error
+		b.checkpoints, err = encode(NewBitWriter(&b.data), b.input, header.Alphabet, codebook.codes, interval)
+		if err != nil {
+			return err
+		}
+		symbols = uint64(header.Alphabet.count(b.input))
+	case Context:
+		ft := NewContextFrequencyTable(header.Alphabet)
+
+		if err := ft.Populate(bytes.NewReader(b.input)); err != nil {
+			return fmt.Errorf("error populating frequency table: %v", err)
+		}
+
+		var err error
+		codes, err = buildContextCodes(ft, header.MaxCodeLength)
+		if err != nil {
+			return fmt.Errorf("failed to build trees: %v", err)
+		}
+
+		if err := encodeContext(NewBitWriter(&b.data), b.input, header.Alphabet, codes); err != nil {
+			return err
+		}
+		symbols = uint64(ft.Total())
+	}
+
+	if err := writeUvarint(&b.output, uint64(len(b.input))); err != nil {
+		return fmt.Errorf("failed to write block length: %v", err)
+	}
+
+	if tree != nil {
+		if err := tree.WriteHeader(NewBitWriter(&b.output), header.Alphabet); err != nil {
+			return fmt.Errorf("failed to write header: %v", err)
+		}
+	}
+
+	if codes != nil {
+		if err := codes.write(NewBitWriter(&b.output), header.Alphabet); err != nil {
+			return fmt.Errorf("failed to write header: %v", err)
+		}
+	}
+
+	// Recording the number of symbols lets the reader stop exactly at the end
+	// of the data rather than decoding the padding of the final byte
+	if err := writeUvarint(&b.output, symbols); err != nil {
+		return fmt.Errorf("failed to write symbol count: %v", err)
+	}
+
+	if err := writeUvarint(&b.output, uint64(b.data.Len())); err != nil {
+		return fmt.Errorf("failed to write data length: %v", err)
+	}
+
+	_, err := b.data.WriteTo(&b.output)
+	return err
+}
+
+// encode writes the code of every symbol in input followed by zero padding.
+// A checkpoint is recorded at the first symbol starting at least interval bytes
+// after the previous one, unless interval is zero.
+func encode(writer *BitWriter, input []byte, alphabet Alphabet, codeTable map[rune]Code, interval int) ([]checkpoint, error) {
+	var checkpoints []checkpoint
+	var bits, offset, symbols uint64
+
+	for next := uint64(interval); len(input) > 0; symbols++ {
+		if interval > 0 && offset >= next {
+			checkpoints = append(checkpoints, checkpoint{bits: bits, offset: offset, symbols: symbols})
+			next = offset + uint64(interval)
+		}
+
+		r, size := alphabet.nextSymbol(input)
+		input = input[size:]
+		offset += uint64(size)
+
+		code, hasRune := codeTable[r]
+		if !hasRune {
+			// Only the trees of shared codebooks lack symbols, and they have
+			// an escape for them
+			escape, hasEscape := codeTable[escapeSymbol]
+			if !hasEscape {
+				return nil, fmt.Errorf("failed to lookup %q", r)
+			}
+			if err := writer.WriteBits(escape.Bits, escape.Length); err != nil {
+				return nil, fmt.Errorf("failed to write escape to output for char %q", r)
+			}
+			bits += uint64(escape.Length)
+			code = Code{Bits: uint64(r), Length: alphabet.symbolBits()}
+		}
+		if err := writer.WriteBits(code.Bits, code.Length); err != nil {
+			return nil, fmt.Errorf("failed to write code to output for char %q", r)
+		}
+		bits += uint64(code.Length)
+	}
+
+	if err := writer.Flush(Zero); err != nil {
+		return nil, fmt.Errorf("failed to flush writer: %v", err)
+	}
+
+	return checkpoints, nil
+}
+
+// encodeAdaptive codes every symbol in input with a new AdaptiveHuffmanTree,
+// followed by zero padding, and returns the number of symbols
+func encodeAdaptive(writer *BitWriter, input []byte, alphabet Alphabet) (uint64, error) {
+	tree := NewAdaptiveHuffmanTree(alphabet)
+
+	var symbols uint64
+	for ; len(input) > 0; symbols++ {
+		r, size := alphabet.nextSymbol(input)
+		input = input[size:]
+
+		if err := tree.Encode(writer, r); err != nil {
+			return 0, fmt.Errorf("failed to write code to output for char %q", r)
+		}
+	}
+
+	if err := writer.Flush(Zero); err != nil {
+		return 0, fmt.Errorf("failed to flush writer: %v", err)
+	}
+
+	return symbols, nil
+}
+
+func writeUvarint(w io.ByteWriter, v uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, b := range buf[:binary.PutUvarint(buf, v)] {
+		if err := w.WriteByte(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
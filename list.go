@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cchuffman/huffman"
+)
+
+// listing prints the sizes of compressed files in the layout of gzip -l,
+// followed by a total when there are several
+type listing struct {
+	opts         options
+	files        int
+	compressed   int64
+	uncompressed int64
+}
+
+// add prints the sizes of the compressed file name, or of stdin for "-". Only
+// indexed streams record the uncompressed size where it can be read directly,
+// so other streams are decoded to find it, which also verifies them.
+func (l *listing) add(name string) error {
+	f := os.Stdin
+	if name != "-" {
+		var err error
+		if f, err = os.Open(name); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	compressed, uncompressed, indexed, err := indexedSize(f, l.opts)
+	if err != nil {
+		return err
+	}
+	if !indexed {
+		if compressed, uncompressed, err = decompress(io.Discard, f, l.opts); err != nil {
+			return err
+		}
+	}
+
+	if l.files == 0 {
+		fmt.Printf("%19s %19s %6s %s\n", "compressed", "uncompressed", "ratio", "uncompressed_name")
+	}
+	l.print(compressed, uncompressed, strings.TrimSuffix(name, suffix))
+
+	l.files += 1
+	l.compressed += compressed
+	l.uncompressed += uncompressed
+
+	return nil
+}
+
+// indexedSize returns the compressed and uncompressed sizes of the regular
+// file f from its index and trailer, and whether it has them. Streams without
+// an index, and pipes, which cannot be read at random, must be decoded
+// instead.
+func indexedSize(f *os.File, opts options) (int64, int64, bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, 0, false, nil
+	}
+
+	var codebooks []*huffman.SharedCodebook
+	if opts.codebook != nil {
+		codebooks = append(codebooks, opts.codebook)
+	}
+
+	reader, err := huffman.NewBlockReader(f, info.Size(), codebooks...)
+	if errors.Is(err, huffman.ErrNoIndex) {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, describeReadError(err)
+	}
+
+	return info.Size(), int64(reader.Size), true, nil
+}
+
+func (l *listing) printTotal() {
+	if l.files > 1 {
+		l.print(l.compressed, l.uncompressed, "(totals)")
+	}
+}
+
+func (l *listing) print(compressed, uncompressed int64, name string) {
+	fmt.Printf("%19d %19d %5.1f%% %s\n", compressed, uncompressed, ratio(uncompressed, compressed), name)
+}
@@ -1,28 +1,544 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"cchuffman/huffman"
+)
+
+// suffix is appended to the names of compressed files, or gzipSuffix with
+// -gzip
+const (
+	suffix     = ".huf"
+	gzipSuffix = ".gz"
+)
+
+// Exit statuses follow gzip: 1 if any file failed and otherwise 2 if any file
+// was skipped with a warning
+const (
+	exitError   = 1
+	exitWarning = 2
 )
 
+// warning is an error that skips a file without failing the run
+type warning string
+
+func (w warning) Error() string {
+	return string(w)
+}
+
+// options holds the flags that apply to every file
+type options struct {
+	decompress  bool
+	keep        bool
+	force       bool
+	stdout      bool
+	test        bool
+	list        bool
+	verbose     bool
+	gzip        bool
+	header      huffman.Header
+	codebook    *huffman.SharedCodebook
+	concurrency int
+}
+
 func main() {
-	input := flag.String("input", "les-mis.txt", "the input file to encode")
-	output := flag.String("output", "output.txt", "the output filepath")
-	decompress := flag.Bool("decompress", false, "treat the input file as compressed")
+	log.SetFlags(0)
+	log.SetPrefix(filepath.Base(os.Args[0]) + ": ")
+
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+		if err := convert(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "extract" {
+		if err := extract(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		if err := stats(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "train" {
+		if err := train(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "evaluate" {
+		if err := evaluate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	opts := options{}
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+
+	// Every option has gzip's single letter along with its long name
+	boolFlag := func(p *bool, short, long, usage string) {
+		flag.BoolVar(p, short, false, usage)
+		flag.BoolVar(p, long, false, usage)
+	}
+	boolFlag(&opts.decompress, "d", "decompress", "decompress, detecting the format from the magic bytes")
+	boolFlag(&opts.keep, "k", "keep", "keep the input files")
+	boolFlag(&opts.force, "f", "force", "overwrite existing output files and write compressed data to a terminal")
+	boolFlag(&opts.stdout, "c", "stdout", "write to stdout and keep the input files")
+	boolFlag(&opts.test, "t", "test", "test the integrity of compressed files")
+	boolFlag(&opts.list, "l", "list", "list the compressed and uncompressed sizes of compressed files")
+	boolFlag(&opts.verbose, "v", "verbose", "report the compression ratio of each file")
+
+	binary := flag.Bool("bytes", false, "code bytes rather than UTF-8 characters, which preserves binary input")
+	maxCodeLength := flag.Int("max-code-length", 0, "the maximum length of a code in bits (0 for no limit)")
+	blockSize := flag.Int("block-size", huffman.DefaultBlockSize, "the number of bytes coded with the same tree")
+	flag.IntVar(&opts.concurrency, "concurrency", runtime.GOMAXPROCS(0), "the number of blocks coded or, for indexed files, decoded at once")
+	adaptive := flag.Bool("adaptive", false, "code with a tree updated after every symbol instead of one stored ahead of each block")
+	context := flag.Bool("context", false, "code each symbol with a tree for the symbol before it, stored ahead of each block along with an order-0 tree")
+	index := flag.Bool("index", false, "append an index of the blocks, which lets them be decoded concurrently")
+	flag.BoolVar(&opts.gzip, "gzip", false, "write gzip files of Huffman-only DEFLATE blocks, which gunzip decompresses, to file"+gzipSuffix)
+	codebook := flag.String("codebook", "", "code with the shared codebook saved by train, which compressed files then need to be decoded")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s convert [-codebook file] file...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s extract [-codebook file] -offset n -length n file\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s stats [-format text|json|csv] [file]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s evaluate -train file [-format text|json|csv] file...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s train -output codebook file...\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Files are compressed to file%s, or decompressed from it, in place of the original. With no files, or -, stdin is coded to stdout.\n", suffix)
+		fmt.Fprintf(flag.CommandLine.Output(), "The exit status is %d if any file failed and %d if any was skipped with a warning.\n\n", exitError, exitWarning)
+		flag.PrintDefaults()
+	}
+
+	parseFlags(flag.CommandLine, splitShortFlags(os.Args[1:]))
+
+	opts.header = huffman.Header{
+		Alphabet:      huffman.Runes,
+		MaxCodeLength: *maxCodeLength,
+		BlockSize:     *blockSize,
+		Indexed:       *index,
+	}
+	if *binary {
+		opts.header.Alphabet = huffman.Bytes
+	}
+	if *adaptive && *context {
+		log.Fatal("-adaptive and -context cannot be combined")
+	}
+	if *adaptive {
+		opts.header.Coding = huffman.Adaptive
+	}
+	if *context {
+		opts.header.Coding = huffman.Context
+	}
+	if opts.gzip {
+		// DEFLATE codes bytes with static trees of at most 15 bits, so there
+		// is nothing else to choose
+		switch {
+		case opts.decompress || opts.test || opts.list:
+			log.Fatal("-gzip only applies to compression; decompress with gunzip")
+		case *adaptive || *context || *index || *codebook != "" || *maxCodeLength != 0:
+			log.Fatal("-gzip cannot be combined with -adaptive, -context, -index, -codebook or -max-code-length")
+		}
+	}
+	if *codebook != "" {
+		var err error
+		if opts.codebook, err = readCodebook(*codebook); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var l *listing
+	if opts.list {
+		l = &listing{opts: opts}
+	}
+
+	status := 0
+	for _, name := range files {
+		var err error
+		switch {
+		case opts.list:
+			err = l.add(name)
+		case opts.decompress || opts.test:
+			err = decompressFile(name, opts)
+		default:
+			err = compressFile(name, opts)
+		}
+
+		var w warning
+		if errors.As(err, &w) {
+			log.Printf("%s: %v", displayName(name), err)
+			if status == 0 {
+				status = exitWarning
+			}
+		} else if err != nil {
+			log.Printf("%s: %v", displayName(name), err)
+			status = exitError
+		}
+	}
+
+	if l != nil {
+		l.printTotal()
+	}
+
+	os.Exit(status)
+}
+
+// parseFlags parses args into flags, which must continue on error, and exits
+// with exitError if they are invalid, rather than with the flag package's
+// status of 2, which here means a skipped file. The flag package has already
+// reported the problem.
+func parseFlags(flags *flag.FlagSet, args []string) {
+	switch err := flags.Parse(args); {
+	case err == flag.ErrHelp:
+		os.Exit(0)
+	case err != nil:
+		os.Exit(exitError)
+	}
+}
+
+// shortFlags are the single letter options that, as with gzip, can be combined
+// into one argument
+const shortFlags = "dkfctlv"
+
+// valueFlags are the options that take their value from the following
+// argument unless it is given as -name=value
+var valueFlags = map[string]bool{
+	"block-size":      true,
+	"max-code-length": true,
+	"concurrency":     true,
+	"codebook":        true,
+}
+
+// splitShortFlags splits combined single letter options such as -dc into -d
+// -c, up to the first argument that is neither an option nor the value of one
+func splitShortFlags(args []string) []string {
+	split := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || len(arg) == 1 {
+			return append(split, args[i:]...)
+		}
+
+		if valueFlags[strings.TrimLeft(arg, "-")] && i+1 < len(args) {
+			split = append(split, arg, args[i+1])
+			i++
+			continue
+		}
+
+		letters := arg[1:]
+		if len(letters) < 2 || strings.Trim(letters, shortFlags) != "" {
+			split = append(split, arg)
+			continue
+		}
+		for _, letter := range letters {
+			split = append(split, "-"+string(letter))
+		}
+	}
+	return split
+}
 
-	flag.Parse()
+// compressFile compresses name to name.huf, or name.gz with -gzip, or stdin
+// to stdout for "-"
+func compressFile(name string, opts options) error {
+	if (name == "-" || opts.stdout) && isTerminal(os.Stdout) && !opts.force {
+		return fmt.Errorf("compressed data not written to a terminal; use -f to force compression")
+	}
+
+	if name == "-" {
+		in, out, err := compress(os.Stdout, os.Stdin, opts)
+		if err == nil {
+			logRatio(opts, name, in, out, "")
+		}
+		return err
+	}
+
+	ext := suffix
+	if opts.gzip {
+		ext = gzipSuffix
+	}
+	if !opts.stdout && strings.HasSuffix(name, ext) {
+		return warning(fmt.Sprintf("already has %s suffix -- unchanged", ext))
+	}
 
-	if !*decompress {
-		encoder := NewHuffmanEncoder(*input, *output)
+	return codeFile(name, name+ext, opts, func(w io.Writer, f *os.File) (int64, int64, error) {
+		return compress(w, f, opts)
+	})
+}
 
-		if err := encoder.Encode(); err != nil {
-			log.Fatalf("failed to compress %s: %v", *input, err)
+// decompressFile decompresses name.huf to name, or stdin to stdout for "-".
+// With -t the data is decoded and discarded.
+func decompressFile(name string, opts options) error {
+	if name == "-" {
+		w := io.Writer(os.Stdout)
+		if opts.test {
+			w = io.Discard
 		}
+		in, out, err := decompress(w, os.Stdin, opts)
+		if err == nil {
+			logRatio(opts, name, out, in, "")
+		}
+		return err
+	}
+
+	if opts.test {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		in, out, err := decompress(io.Discard, f, opts)
+		if err == nil {
+			logRatio(opts, name, out, in, "OK")
+		}
+		return err
+	}
+
+	output := strings.TrimSuffix(name, suffix)
+	if output == name && !opts.stdout {
+		return warning("unknown suffix -- ignored")
+	}
+
+	return codeFile(name, output, opts, func(w io.Writer, f *os.File) (int64, int64, error) {
+		return decompress(w, f, opts)
+	})
+}
+
+// codeFile codes the regular file name into output, or to stdout with -c. The
+// output takes the mode and modification time of the input, which is then
+// removed unless it is to be kept. A partial output is removed on failure.
+func codeFile(name, output string, opts options, code func(w io.Writer, f *os.File) (int64, int64, error)) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return warning("not a regular file -- ignored")
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if opts.stdout {
+		in, out, err := code(os.Stdout, f)
+		if err == nil {
+			if opts.decompress {
+				in, out = out, in
+			}
+			logRatio(opts, name, in, out, "")
+		}
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if opts.force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	outputFile, err := os.OpenFile(output, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return warning(fmt.Sprintf("%s already exists -- not overwritten; use -f to overwrite it", output))
+	}
+	if err != nil {
+		return err
+	}
+
+	in, out, err := code(outputFile, f)
+	if closeErr := outputFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	if err := os.Chmod(output, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(output, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+
+	if !opts.keep {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+
+	if opts.decompress {
+		in, out = out, in
+	}
+	if opts.keep {
+		logRatio(opts, name, in, out, "created "+output)
 	} else {
-		decoder := NewHuffmanDecoder(*input, *output)
+		logRatio(opts, name, in, out, "replaced with "+output)
+	}
+
+	return nil
+}
 
-		if err := decoder.Decode(); err != nil {
-			log.Fatalf("failed to decompress %s: %v", *input, err)
+// compress streams r through a Writer, which buffers it a block at a time
+// rather than rereading it, so a pipe works as well as a file. It returns the
+// number of bytes read and written.
+func compress(w io.Writer, r io.Reader, opts options) (int64, int64, error) {
+	counter := &countingWriter{w: w}
+
+	if opts.gzip {
+		writer := huffman.NewGzipWriter(counter)
+		writer.BlockSize = opts.header.BlockSize
+
+		n, err := io.Copy(writer, r)
+		if err != nil {
+			return n, counter.n, err
 		}
+		return n, counter.n, writer.Close()
+	}
+
+	writer := huffman.NewWriter(counter)
+	writer.Header = opts.header
+	writer.Concurrency = opts.concurrency
+	writer.SharedCodebook = opts.codebook
+
+	n, err := io.Copy(writer, r)
+	if err != nil {
+		return n, counter.n, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return n, counter.n, err
+	}
+
+	return n, counter.n, nil
+}
+
+// decompress decodes f to w, detecting the format from the magic bytes, and
+// returns the number of bytes read and written
+func decompress(w io.Writer, f *os.File, opts options) (int64, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var codebooks []*huffman.SharedCodebook
+	if opts.codebook != nil {
+		codebooks = append(codebooks, opts.codebook)
+	}
+
+	counter := &countingReader{r: f}
+
+	reader, err := huffman.NewReader(counter, codebooks...)
+	if err != nil {
+		return counter.n, 0, describeReadError(err)
+	}
+
+	if reader.Indexed && info.Mode().IsRegular() {
+		// The index lets the blocks be decoded concurrently straight from the
+		// file instead. Pipes cannot be read at random, so they are decoded
+		// in order like any other stream.
+		blockReader, err := huffman.NewBlockReader(f, info.Size(), codebooks...)
+		if err != nil {
+			return counter.n, 0, err
+		}
+		blockReader.Concurrency = opts.concurrency
+
+		n, err := blockReader.WriteTo(w)
+		return info.Size(), n, err
+	}
+
+	n, err := io.Copy(w, reader)
+	return counter.n, n, err
+}
+
+// describeReadError explains the errors of opening a stream that the user can
+// do something about
+func describeReadError(err error) error {
+	switch {
+	case errors.Is(err, huffman.ErrHeader):
+		return fmt.Errorf("not in %s format", suffix)
+	case errors.Is(err, huffman.ErrCodebook):
+		return fmt.Errorf("%v; pass it with -codebook", err)
+	default:
+		return err
+	}
+}
+
+// countingWriter and countingReader count the bytes passed through them,
+// which works for pipes as well as files
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// logRatio reports how much a file shrank to stderr with -v, leaving stdout to
+// the data
+func logRatio(opts options, name string, original, compressed int64, note string) {
+	if !opts.verbose {
+		return
+	}
+	if note != "" {
+		note = " -- " + note
+	}
+	log.Printf("%s: %5.1f%%%s", displayName(name), ratio(original, compressed), note)
+}
+
+// ratio is the space saved as a percentage of the original size
+func ratio(original, compressed int64) float64 {
+	if original == 0 {
+		return 0
+	}
+	return 100 * float64(original-compressed) / float64(original)
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func displayName(name string) string {
+	if name == "-" {
+		return "stdin"
 	}
+	return name
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShortFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"-kv", "file"}, []string{"-k", "-v", "file"}},
+		{[]string{"-d", "-c", "file"}, []string{"-d", "-c", "file"}},
+		{[]string{"-block-size", "100000", "-kv", "file"}, []string{"-block-size", "100000", "-k", "-v", "file"}},
+		{[]string{"--codebook", "cb.hcb", "-dc", "file.huf"}, []string{"--codebook", "cb.hcb", "-d", "-c", "file.huf"}},
+		{[]string{"-concurrency=4", "-kv", "file"}, []string{"-concurrency=4", "-k", "-v", "file"}},
+		{[]string{"-max-code-length", "12", "-concurrency", "2", "-fk", "-"}, []string{"-max-code-length", "12", "-concurrency", "2", "-f", "-k", "-"}},
+		// Only letters that are all short options are split
+		{[]string{"-bytes", "-kx", "file"}, []string{"-bytes", "-kx", "file"}},
+		// Nothing after the first file or -- is an option
+		{[]string{"file", "-kv"}, []string{"file", "-kv"}},
+		{[]string{"-k", "--", "-kv"}, []string{"-k", "--", "-kv"}},
+		{[]string{"-block-size"}, []string{"-block-size"}},
+	}
+
+	for _, test := range tests {
+		if split := splitShortFlags(test.args); !reflect.DeepEqual(split, test.expected) {
+			t.Errorf("Expected %q to split into %q but received %q", test.args, test.expected, split)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"cchuffman/huffman"
+)
+
+// stats reports how well a file's symbols would compress: the code of every
+// symbol and how close the code comes to the entropy
+func stats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s stats [-format text|json|csv] [file]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	format := flags.String("format", "text", "the output format: text, json or csv")
+	binary := flags.Bool("bytes", false, "count bytes rather than UTF-8 characters")
+	maxCodeLength := flags.Int("max-code-length", 0, "the maximum length of a code in bits (0 for no limit)")
+	blockSize := flags.Int("block-size", huffman.DefaultBlockSize, "the number of bytes coded with the same tree")
+
+	parseFlags(flags, args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a single file")
+	}
+	if *blockSize < 0 {
+		return fmt.Errorf("block size must be positive")
+	}
+
+	var print func(w io.Writer, s *huffman.Stats) error
+	switch *format {
+	case "text":
+		print = printStatsText
+	case "json":
+		print = printStatsJSON
+	case "csv":
+		print = printStatsCSV
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	name := flags.Arg(0)
+	if name == "" {
+		name = "-"
+	}
+
+	alphabet := huffman.Runes
+	if *binary {
+		alphabet = huffman.Bytes
+	}
+
+	ft := huffman.NewBlockFrequencyTable(alphabet, *blockSize)
+	if err := countInto(ft, name); err != nil {
+		return err
+	}
+
+	s, err := ft.Stats(*maxCodeLength)
+	if err != nil {
+		return err
+	}
+
+	return print(os.Stdout, s)
+}
+
+// countFile counts the symbols of the named file, or of stdin for "-"
+func countFile(name string, alphabet huffman.Alphabet) (*huffman.FrequencyTable, error) {
+	ft := huffman.NewFrequencyTable(alphabet)
+	if err := countInto(ft, name); err != nil {
+		return nil, err
+	}
+	return ft, nil
+}
+
+func printStatsText(w io.Writer, s *huffman.Stats) error {
+	writer := tabwriter.NewWriter(w, 1, 1, 2, ' ', 0)
+
+	fmt.Fprintf(writer, "Symbols:\t%d (%d distinct) in %d bytes\n", s.Total, len(s.Symbols), s.Size)
+	fmt.Fprintf(writer, "Entropy:\t%.4f bits/symbol\n", s.Entropy)
+	fmt.Fprintf(writer, "Average code length:\t%.4f bits/symbol\n", s.AverageCodeLength)
+	fmt.Fprintf(writer, "Redundancy:\t%.4f bits/symbol\n", s.Redundancy)
+	fmt.Fprintf(writer, "Kraft sum:\t%.4f\n", s.KraftSum)
+	fmt.Fprintf(writer, "Code lengths:\t%d to %d bits\n", s.MinCodeLength, s.MaxCodeLength)
+	fmt.Fprintf(writer, "Predicted size:\t%d bytes (%.1f%% of the original)\n\n", s.PredictedSize, percentage(s.PredictedSize, s.Size))
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	writer = tabwriter.NewWriter(w, 1, 1, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(writer, "Symbol\tCount\tProbability\tLength\tCode\t\n")
+	for _, symbol := range s.Symbols {
+		fmt.Fprintf(writer, "%s\t%d\t%.6f\t%d\t%s\t\n", symbol.Symbol, symbol.Count, symbol.Probability, symbol.CodeLength, symbol.Code)
+	}
+
+	return writer.Flush()
+}
+
+func printStatsJSON(w io.Writer, s *huffman.Stats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// printStatsCSV writes the summary as metric,value records and then, after an
+// empty line, a record for every symbol
+func printStatsCSV(w io.Writer, s *huffman.Stats) error {
+	writer := csv.NewWriter(w)
+
+	float := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	records := [][]string{
+		{"metric", "value"},
+		{"total", strconv.Itoa(s.Total)},
+		{"distinct", strconv.Itoa(len(s.Symbols))},
+		{"size", strconv.FormatUint(s.Size, 10)},
+		{"entropy", float(s.Entropy)},
+		{"average_code_length", float(s.AverageCodeLength)},
+		{"redundancy", float(s.Redundancy)},
+		{"kraft_sum", float(s.KraftSum)},
+		{"predicted_size", strconv.FormatUint(s.PredictedSize, 10)},
+		{"max_code_length", strconv.Itoa(s.MaxCodeLength)},
+		{"min_code_length", strconv.Itoa(s.MinCodeLength)},
+		{},
+		{"symbol", "count", "probability", "code_length", "code"},
+	}
+	for _, symbol := range s.Symbols {
+		records = append(records, []string{symbol.Symbol, strconv.Itoa(symbol.Count), float(symbol.Probability), strconv.Itoa(symbol.CodeLength), symbol.Code})
+	}
+
+	return writer.WriteAll(records)
+}
+
+// percentage returns part as a percentage of whole
+func percentage(part, whole uint64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(whole)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"cchuffman/huffman"
+)
+
+// train builds a shared codebook from the symbols of sample files and saves
+// it, so that small files compressed with -codebook need no tree of their own
+func train(args []string) error {
+	flags := flag.NewFlagSet("train", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s train -output codebook file...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	output := flags.String("output", "", "the file to save the codebook to")
+	binary := flags.Bool("bytes", false, "code bytes rather than UTF-8 characters")
+	maxCodeLength := flags.Int("max-code-length", 0, "the maximum length of a code in bits (0 for no limit)")
+
+	parseFlags(flags, args)
+
+	if *output == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a codebook to save and samples to train it on")
+	}
+
+	alphabet := huffman.Runes
+	if *binary {
+		alphabet = huffman.Bytes
+	}
+
+	ft := huffman.NewFrequencyTable(alphabet)
+	for _, name := range flags.Args() {
+		if err := countInto(ft, name); err != nil {
+			return fmt.Errorf("failed to count %s: %v", name, err)
+		}
+	}
+	if ft.Total() == 0 {
+		return fmt.Errorf("the samples are empty")
+	}
+
+	trained, err := huffman.NewTrainedCode(ft, *maxCodeLength)
+	if err != nil {
+		return err
+	}
+
+	cb, err := huffman.NewSharedCodebook(trained)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := cb.WriteTo(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Trained codebook %08x on %d symbols of %d files and saved it to %s", cb.ID, ft.Total(), flags.NArg(), *output)
+
+	return nil
+}
+
+// countInto adds the symbols of the named file, or of stdin for "-", to ft
+func countInto(ft *huffman.FrequencyTable, name string) error {
+	f := os.Stdin
+	if name != "-" {
+		var err error
+		if f, err = os.Open(name); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	return ft.Populate(f)
+}
+
+// readCodebook loads the codebook saved by train to the named file
+func readCodebook(name string) (*huffman.SharedCodebook, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cb, err := huffman.ReadSharedCodebook(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read codebook %s: %v", name, err)
+	}
+
+	return cb, nil
+}
+
+// readCodebooks reads the codebook saved in name for the subcommands that
+// decode, returning none if name is empty
+func readCodebooks(name string) ([]*huffman.SharedCodebook, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	cb, err := readCodebook(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*huffman.SharedCodebook{cb}, nil
+}